@@ -0,0 +1,974 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mah0x211/mixdown/file"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/theme"
+	"github.com/mah0x211/mixdown/util"
+)
+
+// Builder builds the site from the tracked files and the theme
+type Builder struct {
+	Config
+	Hooks Hooks
+
+	SitemapBuf *bytes.Buffer
+	Theme      *theme.Theme
+	Hashtags   []string
+	Documents  []*file.TrackedFile
+	Resources  []*file.TrackedFile
+	Readme     *file.TrackedFile
+	Outputs    []*Output
+
+	site *Site
+	plan *plan
+}
+
+// Hooks is the set of functions to customize the build. the hooks are called
+// concurrently from up to Config.Jobs goroutines, so they must be safe for
+// concurrent use, e.g. guard the shared state with a mutex.
+type Hooks struct {
+	// Data returns the custom data of the page that is rendered into
+	// pathname by the target, it is exposed to templates as .Custom. it is
+	// called concurrently for the pages of the same target, and the data
+	// must not be modified after it is returned since the page may be
+	// rendered at the same time.
+	Data func(target, pathname string) (interface{}, error)
+}
+
+// Output is the representation of a file generated into outdir
+type Output struct {
+	Pathname string `json:"pathname"`
+	Target   string `json:"target"`
+	Template string `json:"template,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
+
+// Report is the result of the build
+type Report struct {
+	OutDir   string
+	DryRun   bool
+	Outputs  []*Output
+	Plan     []*PlanEntry
+	Duration time.Duration
+}
+
+// ManifestFile is the filename of the list of outputs in outdir, that also
+// marks the directory as created by mixdown
+const ManifestFile string = ".mixdown-manifest.json"
+
+const (
+	pageTypeHome = iota + 1
+	pageTypeArticle
+	pageTypeArchive
+	pageTypeTag
+	pageTypeNotFound
+	pageTypePage
+)
+
+// names of the templates of the pages that mixdown renders, the other
+// templates of the theme are rendered as the standalone pages
+var pageTemplates = []string{"home", "article", "archive", "tag", "404"}
+
+// returns true if name is one of pageTemplates
+func isPageTemplate(name string) bool {
+	for _, v := range pageTemplates {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns the builder of the verified copy of cfg
+func New(cfg *Config) (*Builder, error) {
+	b := &Builder{
+		Config: *cfg,
+	}
+	if err := b.Verify(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// returns the custom data of the page
+func (b *Builder) customData(target, pathname string) (interface{}, error) {
+	if b.Hooks.Data == nil {
+		return nil, nil
+	}
+	data, err := b.Hooks.Data(target, pathname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Hooks.Data(): %s", err)
+	}
+	return data, nil
+}
+
+// render sitemap
+func (b *Builder) renderSitemap(pathname string) error {
+	if b.SitemapBuf != nil {
+		// remove outdir prefix
+		pathname = filepath.Join(b.BaseURL, strings.TrimPrefix(pathname, b.OutDir))
+		// escape-uri-component
+		segs := make([]string, 1)
+		for _, seg := range strings.Split(pathname, "/") {
+			segs = append(segs, url.PathEscape(seg))
+		}
+		uri := b.Sitemap + "/" + filepath.Join(segs...) + "\n"
+		if _, err := b.SitemapBuf.WriteString(uri); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add a generated file to outputs
+func (b *Builder) addOutput(pathname, target, tmpl, src string, elapsed time.Duration) {
+	rel, err := filepath.Rel(b.OutDir, pathname)
+	if err != nil {
+		rel = pathname
+	}
+	logger.File(logger.Event{
+		Source:   src,
+		Output:   rel,
+		Target:   target,
+		Duration: elapsed,
+	})
+	b.Outputs = append(b.Outputs, &Output{
+		Pathname: rel,
+		Target:   target,
+		Template: tmpl,
+		Source:   src,
+	})
+}
+
+// write the list of outputs into outdir
+func (b *Builder) writeManifest() error {
+	pathname := filepath.Join(b.OutDir, ManifestFile)
+	if buf, err := json.MarshalIndent(b.Outputs, "", "    "); err != nil {
+		return fmt.Errorf("failed to json.MarshalIndent(): %s", err)
+	} else if err = ioutil.WriteFile(pathname, buf, 0644); err != nil {
+		return fmt.Errorf("failed to ioutil.WriteFile(): %s", err)
+	}
+	return nil
+}
+
+// write data into pathname, or add it to the plan in dry-run mode
+func (b *Builder) writeFile(pathname string, data []byte) error {
+	if b.plan != nil {
+		b.plan.add(pathname, data)
+		return nil
+	}
+
+	if ofile, err := util.CreateFile(pathname); err != nil {
+		return fmt.Errorf("error util.CreateFile(): %s", err)
+	} else if _, err = ofile.Write(data); err != nil {
+		ofile.Close()
+		return fmt.Errorf("error File.Write(): %s", err)
+	} else {
+		return ofile.Close()
+	}
+}
+
+// copy srcpath file to pathname, or add it to the plan in dry-run mode
+func (b *Builder) copyFile(srcpath, pathname string) error {
+	if b.plan != nil {
+		if data, err := ioutil.ReadFile(srcpath); err != nil {
+			return fmt.Errorf("error ioutil.ReadFile(): %s", err)
+		} else {
+			b.plan.add(pathname, data)
+		}
+		return nil
+	}
+
+	if err := util.CopyFile(srcpath, pathname); err != nil {
+		return fmt.Errorf("error util.CopyFile(): %s", err)
+	}
+	return nil
+}
+
+// pageOutput is the output file of the template of the extension
+type pageOutput struct {
+	ext      string
+	pathname string
+}
+
+// returns the output files of the named template. the template of extname, or
+// the first html template if there is no such template, is rendered into
+// pathname, and the alternate templates are rendered into pathname with their
+// extension, e.g. home.mix.xml is rendered into index.xml.
+func (b *Builder) pageOutputs(pathname, name string) []pageOutput {
+	exts := b.Theme.Exts(name)
+	primary := ""
+	for _, ext := range exts {
+		if ext == b.Extname {
+			primary = ext
+			break
+		} else if primary == "" && theme.IsHTMLExt(ext) {
+			primary = ext
+		}
+	}
+
+	outputs := make([]pageOutput, 0, len(exts))
+	for _, ext := range exts {
+		out := pageOutput{ext: ext, pathname: pathname}
+		if ext != primary {
+			out.pathname = strings.TrimSuffix(pathname, filepath.Ext(pathname)) + "." + ext
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs
+}
+
+// render a named template with data into the output files of pathname
+func (b *Builder) renderPage(pathname, name string, data interface{}) error {
+	for _, out := range b.pageOutputs(pathname, name) {
+		if err := b.renderTemplate(out.pathname, name, out.ext, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render a named template of the output extension with data into pathname
+func (b *Builder) renderTemplate(pathname, name, ext string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := b.Theme.Execute(&buf, name, ext, data); err != nil {
+		return fmt.Errorf("error Template.Execute(): %s", err)
+	}
+	return b.writeFile(pathname, buf.Bytes())
+}
+
+// add the output files of pathname rendered by the named template to outputs,
+// and the html outputs to the sitemap if sitemap is true
+func (b *Builder) addPageOutputs(pathname, target, name, src string, elapsed time.Duration, sitemap bool) error {
+	for _, out := range b.pageOutputs(pathname, name) {
+		b.addOutput(out.pathname, target, name, src, elapsed)
+		if sitemap && theme.IsHTMLExt(out.ext) {
+			if err := b.renderSitemap(out.pathname); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// render tags
+func (b *Builder) renderTags(ctx context.Context) error {
+	type stTag struct {
+		BaseURL  string
+		Site     *Site
+		PageType int
+		Page     int
+		NPage    *int
+		Readme   *file.TrackedFile
+		Hashtags []string
+		Href     string
+		Pathname string
+		Subject  string
+		Docs     []*file.TrackedFile
+		Newer    *stTag
+		Older    *stTag
+		Custom   interface{}
+	}
+
+	// grouping files with hashtags
+	tags := make(map[string]*stTag)
+	ndoc := b.NArchive
+	tagExists := make(map[string]bool)
+	for _, doc := range b.Documents {
+		// maintain references for readme.html
+		if strings.HasPrefix(doc.Source, "README.") {
+			b.Readme = doc
+		}
+
+		// grouping with hashtags
+		for _, hashtag := range doc.Hashtags {
+			tagName := hashtag[1:]
+			href := filepath.Join(b.BaseURL, "t", url.PathEscape(tagName)) + "/"
+			// the summary is escaped html
+			doc.Summary = template.HTML(strings.Replace(
+				string(doc.Summary), html.EscapeString(hashtag),
+				fmt.Sprintf(
+					"<a href=\"%s\">%s</a>",
+					html.EscapeString(href), html.EscapeString(hashtag),
+				), 1,
+			))
+
+			// insert hashtag into list
+			if _, ok := tagExists[hashtag]; !ok {
+				tagExists[hashtag] = true
+				b.Hashtags = append(b.Hashtags, tagName)
+			}
+
+			if tag, ok := tags[hashtag]; ok {
+				// create next page
+				if len(tag.Docs) == ndoc {
+					*tag.NPage++
+					pageName := strconv.Itoa(*tag.NPage) + "." + b.Extname
+					tag.Older = &stTag{
+						BaseURL:  b.BaseURL,
+						Site:     b.site,
+						PageType: pageTypeTag,
+						Page:     *tag.NPage,
+						NPage:    tag.NPage,
+						Href:     filepath.Join(href, pageName),
+						Pathname: filepath.Join("t", tagName, pageName),
+						Subject:  hashtag,
+						Newer:    tag,
+					}
+					tag = tag.Older
+				}
+				tag.Docs = append(tag.Docs, doc)
+
+			} else {
+				page := 1
+				tags[hashtag] = &stTag{
+					BaseURL:  b.BaseURL,
+					Site:     b.site,
+					PageType: pageTypeTag,
+					Page:     page,
+					NPage:    &page,
+					Href:     filepath.Join(href, "index."+b.Extname),
+					Pathname: filepath.Join("t", tagName, "index."+b.Extname),
+					Subject:  hashtag,
+					Docs:     []*file.TrackedFile{doc},
+				}
+			}
+		}
+	}
+
+	// sort hashtags by name
+	sort.Slice(b.Hashtags, func(i, j int) bool {
+		return b.Hashtags[i] < b.Hashtags[j]
+	})
+
+	// list pages of tags in order of name
+	names := make([]string, 0, len(tags))
+	for hashtag := range tags {
+		names = append(names, hashtag)
+	}
+	sort.Strings(names)
+	pages := make([]*stTag, 0, len(names))
+	for _, hashtag := range names {
+		for tag := tags[hashtag]; tag != nil; tag = tag.Older {
+			tag.Readme = b.Readme
+			tag.Hashtags = b.Hashtags
+			pages = append(pages, tag)
+		}
+	}
+
+	// render tags
+	elapsed := make([]time.Duration, len(pages))
+	return b.parallel(ctx, len(pages), func(i int) error {
+		defer func(start time.Time) { elapsed[i] = time.Since(start) }(time.Now())
+		custom, err := b.customData("tag", pages[i].Pathname)
+		if err != nil {
+			return err
+		}
+		pages[i].Custom = custom
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		return b.renderPage(pathname, "tag", pages[i])
+	}, func(i int) error {
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		return b.addPageOutputs(pathname, "tag", "tag", pages[i].Subject, elapsed[i], true)
+	})
+}
+
+// render articles
+func (b *Builder) renderArticles(ctx context.Context) error {
+	type stArticle struct {
+		*file.TrackedFile
+		BaseURL  string
+		Site     *Site
+		PageType int
+		Readme   *file.TrackedFile
+		Hashtags []string
+		Custom   interface{}
+	}
+
+	elapsed := make([]time.Duration, len(b.Documents))
+	err := b.parallel(ctx, len(b.Documents), func(i int) error {
+		defer func(start time.Time) { elapsed[i] = time.Since(start) }(time.Now())
+		doc := b.Documents[i]
+		custom, err := b.customData("article", doc.Pathname)
+		if err != nil {
+			return err
+		}
+		article := stArticle{
+			doc, b.BaseURL, b.site, pageTypeArticle, b.Readme, b.Hashtags, custom,
+		}
+		pathname := filepath.Join(b.OutDir, doc.Pathname)
+		return b.renderPage(pathname, "article", article)
+	}, func(i int) error {
+		doc := b.Documents[i]
+		pathname := filepath.Join(b.OutDir, doc.Pathname)
+		return b.addPageOutputs(pathname, "article", "article", doc.Source, elapsed[i], true)
+	})
+
+	// the contents of the newer and older documents are read while rendering
+	// the article, so they are unloaded after all articles are rendered
+	for _, doc := range b.Documents {
+		doc.Unload()
+	}
+	return err
+}
+
+// render archives into archive/ directory
+func (b *Builder) renderArchives(ctx context.Context) error {
+	type stArchive struct {
+		BaseURL  string
+		Site     *Site
+		PageType int
+		Page     int
+		NPage    *int
+		Readme   *file.TrackedFile
+		Hashtags []string
+		Href     string
+		Pathname string
+		Subject  string
+		Docs     []*file.TrackedFile
+		First    *file.TrackedFile
+		Last     *file.TrackedFile
+		Newer    *stArchive
+		Older    *stArchive
+		Custom   interface{}
+	}
+
+	// collect files to archive
+	page := 1
+	arc := &stArchive{
+		PageType: pageTypeArchive,
+		BaseURL:  b.BaseURL,
+		Site:     b.site,
+		Page:     page,
+		NPage:    &page,
+		Pathname: filepath.Join("archive", "index."+b.Extname),
+	}
+	arc.Href = filepath.Join(b.BaseURL, arc.Pathname)
+	head := arc
+	ndoc := b.NArchive
+	for _, doc := range b.Documents {
+		// create next page
+		if len(arc.Docs) == ndoc {
+			arc.First, arc.Last = arc.Docs[0], arc.Docs[ndoc-1]
+			page++
+			arc.Older = &stArchive{
+				BaseURL:  b.BaseURL,
+				Site:     b.site,
+				PageType: pageTypeArchive,
+				Page:     page,
+				NPage:    &page,
+				Pathname: filepath.Join("archive", strconv.Itoa(page)+"."+b.Extname),
+				Newer:    arc,
+			}
+			arc = arc.Older
+			arc.Href = filepath.Join(b.BaseURL, arc.Pathname)
+		}
+		arc.Docs = append(arc.Docs, doc)
+	}
+
+	if len(arc.Docs) == 0 {
+		return nil
+	}
+	arc.First, arc.Last = arc.Docs[0], arc.Docs[len(arc.Docs)-1]
+
+	// list pages of archives
+	pages := make([]*stArchive, 0, page)
+	for arc = head; arc != nil; arc = arc.Older {
+		arc.Readme = b.Readme
+		arc.Hashtags = b.Hashtags
+		pages = append(pages, arc)
+	}
+
+	// render archives
+	elapsed := make([]time.Duration, len(pages))
+	return b.parallel(ctx, len(pages), func(i int) error {
+		defer func(start time.Time) { elapsed[i] = time.Since(start) }(time.Now())
+		custom, err := b.customData("archive", pages[i].Pathname)
+		if err != nil {
+			return err
+		}
+		pages[i].Custom = custom
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		return b.renderPage(pathname, "archive", pages[i])
+	}, func(i int) error {
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		return b.addPageOutputs(pathname, "archive", "archive", "", elapsed[i], true)
+	})
+}
+
+// render home
+func (b *Builder) renderHome(ctx context.Context) error {
+	type stHome struct {
+		BaseURL  string
+		Site     *Site
+		PageType int
+		Readme   *file.TrackedFile
+		Hashtags []string
+		Subject  string
+		Docs     []*file.TrackedFile
+		Custom   interface{}
+	}
+
+	start := time.Now()
+	custom, err := b.customData("home", "index."+b.Extname)
+	if err != nil {
+		return err
+	}
+	home := stHome{
+		b.BaseURL, b.site, pageTypeHome, b.Readme, b.Hashtags, "", b.Documents, custom,
+	}
+	pathname := filepath.Join(b.OutDir, "index."+b.Extname)
+
+	// render
+	if err = b.renderPage(pathname, "home", home); err != nil {
+		return err
+	}
+	return b.addPageOutputs(pathname, "home", "home", "", time.Since(start), true)
+}
+
+// render 404 page if the theme has a template for it
+func (b *Builder) renderNotFound(ctx context.Context) error {
+	type stNotFound struct {
+		BaseURL  string
+		Site     *Site
+		PageType int
+		Readme   *file.TrackedFile
+		Hashtags []string
+		Subject  string
+		Custom   interface{}
+	}
+
+	if !b.Theme.Exists("404") {
+		return nil
+	}
+
+	start := time.Now()
+	custom, err := b.customData("404", "404."+b.Extname)
+	if err != nil {
+		return err
+	}
+	notfound := stNotFound{
+		b.BaseURL, b.site, pageTypeNotFound, b.Readme, b.Hashtags, "", custom,
+	}
+	pathname := filepath.Join(b.OutDir, "404."+b.Extname)
+
+	if err = b.renderPage(pathname, "404", notfound); err != nil {
+		return err
+	}
+	return b.addPageOutputs(pathname, "404", "404", "", time.Since(start), false)
+}
+
+// render the standalone pages of the templates that are not pageTemplates into
+// the pathname of the extension of each template, e.g. search.mix.html and
+// feed.mix.xml are rendered into search.html and feed.xml, or the pathname of
+// the route "name.ext" or "name" in the config
+func (b *Builder) renderPages(ctx context.Context) error {
+	type stPage struct {
+		BaseURL   string
+		Site      *Site
+		PageType  int
+		Readme    *file.TrackedFile
+		Hashtags  []string
+		Href      string
+		Pathname  string
+		Subject   string
+		Docs      []*file.TrackedFile
+		Resources []*file.TrackedFile
+		Custom    interface{}
+	}
+
+	for name := range b.Routes {
+		if !b.Theme.Exists(name) && !b.hasTemplateExt(name) {
+			return fmt.Errorf("template %q of the route is not found", name)
+		}
+	}
+
+	// pages must not overwrite the other outputs, including the resources,
+	// sitemap and assets that are written after them
+	outputs := make(map[string]string)
+	for _, out := range b.Outputs {
+		outputs[out.Pathname] = out.Target
+	}
+	for _, rsrc := range b.copiedResources() {
+		outputs[rsrc.Pathname] = "resources"
+	}
+	if b.SitemapBuf != nil {
+		outputs["sitemap.txt"] = "sitemap"
+	}
+	assets, err := b.Theme.AssetFiles()
+	if err != nil {
+		return fmt.Errorf("failed to theme.AssetFiles(): %s", err)
+	}
+	for _, asset := range assets {
+		outputs[asset.Pathname] = "assets"
+	}
+
+	type stOutput struct {
+		name string
+		ext  string
+	}
+	tmpls := []stOutput{}
+	pages := []*stPage{}
+	for _, name := range b.Theme.Names() {
+		if isPageTemplate(name) {
+			continue
+		}
+		for _, ext := range b.Theme.Exts(name) {
+			pathname := name + "." + ext
+			if route, ok := b.Routes[pathname]; ok {
+				pathname = filepath.FromSlash(route)
+			} else if route, ok := b.Routes[name]; ok {
+				pathname = filepath.FromSlash(route)
+				// the route of the name is the pathname of the html output
+				if !theme.IsHTMLExt(ext) {
+					pathname = strings.TrimSuffix(pathname, filepath.Ext(pathname)) + "." + ext
+				}
+			}
+			if target, ok := outputs[pathname]; ok {
+				return fmt.Errorf("page %q conflicts with the %s output %q", name+".mix."+ext, target, pathname)
+			}
+			outputs[pathname] = "page"
+			tmpls = append(tmpls, stOutput{name, ext})
+			pages = append(pages, &stPage{
+				BaseURL:   b.BaseURL,
+				Site:      b.site,
+				PageType:  pageTypePage,
+				Readme:    b.Readme,
+				Hashtags:  b.Hashtags,
+				Href:      filepath.Join(b.BaseURL, pathname),
+				Pathname:  pathname,
+				Docs:      b.Documents,
+				Resources: b.Resources,
+			})
+		}
+	}
+
+	elapsed := make([]time.Duration, len(pages))
+	return b.parallel(ctx, len(pages), func(i int) error {
+		defer func(start time.Time) { elapsed[i] = time.Since(start) }(time.Now())
+		custom, err := b.customData("page", pages[i].Pathname)
+		if err != nil {
+			return err
+		}
+		pages[i].Custom = custom
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		return b.renderTemplate(pathname, tmpls[i].name, tmpls[i].ext, pages[i])
+	}, func(i int) error {
+		pathname := filepath.Join(b.OutDir, pages[i].Pathname)
+		b.addOutput(pathname, "page", tmpls[i].name, "", elapsed[i])
+		if theme.IsHTMLExt(tmpls[i].ext) {
+			return b.renderSitemap(pathname)
+		}
+		return nil
+	})
+}
+
+// returns true if the template of "name.ext" exists
+func (b *Builder) hasTemplateExt(name string) bool {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return false
+	}
+	for _, v := range b.Theme.Exts(strings.TrimSuffix(name, ext)) {
+		if v == ext[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the resources that are copied into outdir, dotfiles are not copied
+func (b *Builder) copiedResources() []*file.TrackedFile {
+	list := make([]*file.TrackedFile, 0, len(b.Resources))
+	for _, rsrc := range b.Resources {
+		if !strings.HasPrefix(filepath.Base(rsrc.Pathname), ".") {
+			list = append(list, rsrc)
+		}
+	}
+	return list
+}
+
+// render resource
+func (b *Builder) renderResources(ctx context.Context) error {
+	list := b.copiedResources()
+	elapsed := make([]time.Duration, len(list))
+	return b.parallel(ctx, len(list), func(i int) error {
+		defer func(start time.Time) { elapsed[i] = time.Since(start) }(time.Now())
+		rsrc := list[i]
+		pathname := filepath.Join(b.OutDir, rsrc.Pathname)
+		defer rsrc.Unload()
+		return b.copyFile(rsrc.Pathname, pathname)
+	}, func(i int) error {
+		rsrc := list[i]
+		pathname := filepath.Join(b.OutDir, rsrc.Pathname)
+		b.addOutput(pathname, "resources", "", rsrc.Source, elapsed[i])
+		return nil
+	})
+}
+
+// run fn in parallel with b.Jobs workers until ctx is done
+func (b *Builder) parallel(ctx context.Context, n int, fn func(i int) error, done func(i int) error) error {
+	return util.Parallel(b.Jobs, n, func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(i)
+	}, done)
+}
+
+// render
+func (b *Builder) render(ctx context.Context, target string) error {
+	switch target {
+	case "tag":
+		return b.renderTags(ctx)
+	case "article":
+		return b.renderArticles(ctx)
+	case "archive":
+		return b.renderArchives(ctx)
+	case "home":
+		return b.renderHome(ctx)
+	case "404":
+		return b.renderNotFound(ctx)
+	case "page":
+		return b.renderPages(ctx)
+	case "resources":
+		return b.renderResources(ctx)
+	default:
+		return fmt.Errorf("unknown target %q", target)
+	}
+}
+
+// Build builds the site into outdir, or plans it without writing files in
+// dry-run mode
+func (b *Builder) Build(ctx context.Context) (*Report, error) {
+	start := time.Now()
+	if err := b.build(ctx); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		OutDir:  b.OutDir,
+		DryRun:  b.DryRun,
+		Outputs: b.Outputs,
+	}
+	if b.plan != nil {
+		entries, err := b.makePlan()
+		if err != nil {
+			return nil, fmt.Errorf("failed to makePlan(): %s", err)
+		}
+		report.Plan = entries
+	}
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+// build the site into outdir
+func (b *Builder) build(ctx context.Context) error {
+	b.Hashtags = nil
+	b.Readme = nil
+	b.Outputs = nil
+
+	b.plan = nil
+	outdir := b.OutDir
+	if b.DryRun {
+		// keep outputs in memory
+		b.plan = newPlan()
+	} else {
+		// verify existing output-dir before any deletion
+		if err := b.verifyOutDir(); err != nil {
+			return fmt.Errorf("failed to verifyOutDir(): %s", err)
+		}
+
+		// create staging-dir and mark it
+		logger.Infof("create staging directory %q", b.siblingDir("staging"))
+		staging, err := b.createStagingDir()
+		if err != nil {
+			return fmt.Errorf("failed to createStagingDir(): %s", err)
+		}
+		defer func() {
+			b.OutDir = outdir
+			os.RemoveAll(staging)
+		}()
+		b.OutDir = staging
+		if err = b.writeManifest(); err != nil {
+			return err
+		}
+	}
+
+	// create sitemap.txt
+	b.SitemapBuf = nil
+	if b.Sitemap != "" {
+		b.SitemapBuf = &bytes.Buffer{}
+	}
+
+	// load theme files
+	logger.Infof("load theme files %q", b.ThemeDir)
+	if t, err := theme.New(b.ThemeDir); err != nil {
+		return fmt.Errorf("failed to theme.New(): %s", err)
+	} else if loc, err := b.Site.Location(); err != nil {
+		return fmt.Errorf("failed to SiteConfig.Location(): %s", err)
+	} else {
+		t.SetLocale(loc, b.Site.Language)
+		b.Theme = t
+	}
+
+	// load data files
+	logger.Infof("load data files %q", b.DataDir)
+	if data, err := loadData(b.DataDir); err != nil {
+		return fmt.Errorf("failed to loadData(): %s", err)
+	} else {
+		b.site = b.newSite(data)
+	}
+
+	// load tracked files
+	logger.Infof("load tracked files")
+	if docs, rsrc, err := file.GetTrackedFiles(b.BaseURL, b.UseEpochname, b.Extname, b.Jobs); err != nil {
+		return fmt.Errorf("failed to file.GetTrackedFiles(): %s", err)
+	} else {
+		b.Documents = docs
+		b.Resources = rsrc
+	}
+
+	// render
+	for _, target := range []string{
+		"tag", "article", "archive", "home", "404", "page", "resources",
+	} {
+		logger.Infof("render %q", target)
+		if err := b.render(ctx, target); err != nil {
+			return fmt.Errorf("failed to render(): %s", err)
+		}
+	}
+
+	// write sitemap.txt
+	if b.SitemapBuf != nil {
+		pathname := filepath.Join(b.OutDir, "sitemap.txt")
+		start := time.Now()
+		if err := b.writeFile(pathname, b.SitemapBuf.Bytes()); err != nil {
+			return err
+		}
+		b.addOutput(pathname, "sitemap", "", "", time.Since(start))
+	}
+
+	// export assets
+	logger.Infof("export assets directories")
+	assets, err := b.Theme.AssetFiles()
+	if err != nil {
+		return fmt.Errorf("failed to theme.AssetFiles(): %s", err)
+	}
+	if b.plan == nil {
+		if err = b.Theme.ExportAssets(b.OutDir); err != nil {
+			return fmt.Errorf("failed to theme.ExportAssets(): %s", err)
+		}
+	}
+	for _, asset := range assets {
+		pathname := filepath.Join(b.OutDir, asset.Pathname)
+		if b.plan != nil {
+			// the asset file may be embedded in the binary
+			if data, err := asset.ReadFile(); err != nil {
+				return fmt.Errorf("failed to AssetFile.ReadFile(): %s", err)
+			} else if err = b.writeFile(pathname, data); err != nil {
+				return err
+			}
+		}
+		b.addOutput(pathname, "assets", "", asset.Source, 0)
+	}
+
+	if b.plan != nil {
+		return nil
+	}
+
+	// write manifest
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	logger.Infof("write manifest %q", ManifestFile)
+	if err = b.writeManifest(); err != nil {
+		return err
+	}
+
+	// replace output-dir with staging-dir
+	if b.Backup != "" {
+		logger.Infof("replace output directory %q and backup to %q", outdir, b.Backup)
+	} else {
+		logger.Infof("replace output directory %q", outdir)
+	}
+	staging := b.OutDir
+	b.OutDir = outdir
+	if err = b.swapOutDir(staging); err != nil {
+		return fmt.Errorf("failed to swapOutDir(): %s", err)
+	}
+	return nil
+}
+
+// ExportAssets exports the asset files of the theme into outdir without
+// rebuilding the pages, and updates the manifest with them. it is used to
+// apply the changes of the asset files after Build.
+func (b *Builder) ExportAssets() error {
+	if b.Theme == nil {
+		return fmt.Errorf("theme is not loaded")
+	}
+
+	assets, err := b.Theme.AssetFiles()
+	if err != nil {
+		return fmt.Errorf("failed to theme.AssetFiles(): %s", err)
+	} else if err = b.Theme.ExportAssets(b.OutDir); err != nil {
+		return fmt.Errorf("failed to theme.ExportAssets(): %s", err)
+	}
+
+	outputs := make(map[string]*Output, len(b.Outputs))
+	for _, out := range b.Outputs {
+		outputs[out.Pathname] = out
+	}
+	for _, asset := range assets {
+		if out, ok := outputs[asset.Pathname]; ok {
+			// the asset file may be overridden by the child theme
+			out.Source = asset.Source
+		} else {
+			b.addOutput(filepath.Join(b.OutDir, asset.Pathname), "assets", "", asset.Source, 0)
+		}
+	}
+
+	logger.Infof("write manifest %q", ManifestFile)
+	return b.writeManifest()
+}
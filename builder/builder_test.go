@@ -0,0 +1,248 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mah0x211/mixdown/file"
+	"github.com/mah0x211/mixdown/theme"
+)
+
+func TestRenderArticlesWithNeighbors(t *testing.T) {
+	themedir := t.TempDir()
+	tmpl := `{{.Content}}|{{with .Newer}}{{.Content}}{{end}}|{{with .Older}}{{.Content}}{{end}}`
+	if err := ioutil.WriteFile(filepath.Join(themedir, "article.mix.html"), []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	th, err := theme.New(themedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Config: *NewConfig(), Theme: th}
+	b.OutDir = t.TempDir()
+	b.Jobs = 8
+	for i := 0; i < 64; i++ {
+		doc := &file.TrackedFile{
+			Pathname: fmt.Sprintf("%d.html", i),
+			Content:  template.HTML(fmt.Sprint(i)),
+		}
+		if i > 0 {
+			doc.Newer = b.Documents[i-1]
+			doc.Newer.Older = doc
+		}
+		b.Documents = append(b.Documents, doc)
+	}
+
+	if err = b.renderArticles(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i, doc := range b.Documents {
+		want := fmt.Sprintf("%d|", i)
+		if i > 0 {
+			want += fmt.Sprint(i - 1)
+		}
+		want += "|"
+		if i < len(b.Documents)-1 {
+			want += fmt.Sprint(i + 1)
+		}
+		if buf, err := ioutil.ReadFile(filepath.Join(b.OutDir, doc.Pathname)); err != nil {
+			t.Fatal(err)
+		} else if string(buf) != want {
+			t.Errorf("%s = %q, want %q", doc.Pathname, buf, want)
+		}
+		if doc.Content != "" {
+			t.Errorf("content of %s is not unloaded", doc.Pathname)
+		}
+	}
+}
+
+func TestExportAssets(t *testing.T) {
+	themedir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(themedir, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"style.css", "app.js"} {
+		if err := ioutil.WriteFile(filepath.Join(themedir, "assets", name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	th, err := theme.New(themedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Config: *NewConfig(), Theme: th}
+	b.OutDir = t.TempDir()
+	b.Outputs = []*Output{
+		{Pathname: "index.html", Target: "home", Template: "home"},
+		{Pathname: filepath.Join("assets", "style.css"), Target: "assets", Source: "old"},
+	}
+	if err = b.ExportAssets(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(b.OutDir, ManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputs []*Output
+	if err = json.Unmarshal(buf, &outputs); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, out := range outputs {
+		got[out.Pathname] = out.Source
+	}
+	for pathname, src := range map[string]string{
+		"index.html":                         "",
+		filepath.Join("assets", "style.css"): filepath.Join(themedir, "assets", "style.css"),
+		filepath.Join("assets", "app.js"):    filepath.Join(themedir, "assets", "app.js"),
+	} {
+		if v, ok := got[pathname]; !ok || v != src {
+			t.Errorf("manifest of %q = %q, want %q", pathname, v, src)
+		}
+		if src != "" {
+			if _, err := os.Stat(filepath.Join(b.OutDir, pathname)); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	if len(outputs) != 3 {
+		t.Errorf("manifest has %d outputs, want 3", len(outputs))
+	}
+}
+
+// returns the builder with the theme of files and the temporary outdir
+func newTestBuilder(t *testing.T, files map[string]string) *Builder {
+	t.Helper()
+	themedir := writeTestDir(t, files)
+	th, err := theme.New(themedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Config: *NewConfig(), Theme: th}
+	b.OutDir = t.TempDir()
+	return b
+}
+
+func TestRenderPages(t *testing.T) {
+	b := newTestBuilder(t, map[string]string{
+		"home.mix.html":   `home`,
+		"feed.mix.xml":    `<feed>{{.Pathname}}</feed>`,
+		"about.mix.html":  `about {{.Href}}`,
+		"about.mix.json":  `{"href": "{{.Href}}"}`,
+		"search.mix.html": `search`,
+		"search.mix.json": `[]`,
+	})
+	b.BaseURL = "/blog/"
+	b.Routes = map[string]string{
+		"about":       "about/index.html",
+		"search.json": "api/search.json",
+	}
+	if err := b.renderPages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for pathname, want := range map[string]string{
+		"feed.xml":                           "<feed>feed.xml</feed>",
+		filepath.Join("about", "index.html"): "about /blog/about/index.html",
+		filepath.Join("about", "index.json"): `{"href": "/blog/about/index.json"}`,
+		"search.html":                        "search",
+		filepath.Join("api", "search.json"):  "[]",
+	} {
+		if buf, err := ioutil.ReadFile(filepath.Join(b.OutDir, pathname)); err != nil {
+			t.Error(err)
+		} else if string(buf) != want {
+			t.Errorf("%s = %q, want %q", pathname, buf, want)
+		}
+	}
+	if len(b.Outputs) != 5 {
+		t.Errorf("renderPages() adds %d outputs, want 5", len(b.Outputs))
+	}
+	for _, out := range b.Outputs {
+		if out.Target != "page" {
+			t.Errorf("target of %q = %q", out.Pathname, out.Target)
+		}
+	}
+}
+
+func TestRenderPagesConflict(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		setup  func(b *Builder)
+		files  map[string]string
+		errmsg string
+	}{
+		{
+			name: "rendered page",
+			setup: func(b *Builder) {
+				b.Outputs = []*Output{{Pathname: "index.html", Target: "home"}}
+				b.Routes = map[string]string{"about": "index.html"}
+			},
+			errmsg: `page "about.mix.html" conflicts with the home output "index.html"`,
+		},
+		{
+			name: "other page",
+			setup: func(b *Builder) {
+				b.Routes = map[string]string{"about": "feed.xml"}
+			},
+			errmsg: `conflicts with the page output "feed.xml"`,
+		},
+		{
+			name: "resource",
+			setup: func(b *Builder) {
+				b.Resources = []*file.TrackedFile{{Pathname: "feed.xml"}}
+			},
+			errmsg: `page "feed.mix.xml" conflicts with the resources output "feed.xml"`,
+		},
+		{
+			name: "dotfile resource",
+			setup: func(b *Builder) {
+				b.Resources = []*file.TrackedFile{{Pathname: ".about.html"}}
+				b.Routes = map[string]string{"about": ".about.html"}
+			},
+		},
+		{
+			name: "sitemap",
+			setup: func(b *Builder) {
+				b.SitemapBuf = &bytes.Buffer{}
+				b.Routes = map[string]string{"about": "sitemap.txt"}
+			},
+			errmsg: `page "about.mix.html" conflicts with the sitemap output "sitemap.txt"`,
+		},
+		{
+			name: "asset",
+			setup: func(b *Builder) {
+				b.Routes = map[string]string{"feed.xml": "assets/feed.xml"}
+			},
+			files:  map[string]string{"assets/feed.xml": "asset"},
+			errmsg: `page "feed.mix.xml" conflicts with the assets output "assets/feed.xml"`,
+		},
+	} {
+		files := map[string]string{
+			"feed.mix.xml":   `feed`,
+			"about.mix.html": `about`,
+		}
+		for name, text := range c.files {
+			files[name] = text
+		}
+		b := newTestBuilder(t, files)
+		c.setup(b)
+		err := b.renderPages(context.Background())
+		if c.errmsg == "" && err != nil {
+			t.Errorf("%s: renderPages() returns %s", c.name, err)
+		} else if c.errmsg != "" && (err == nil || !strings.Contains(err.Error(), filepath.FromSlash(c.errmsg))) {
+			t.Errorf("%s: renderPages() returns %v, want %q", c.name, err, c.errmsg)
+		}
+	}
+}
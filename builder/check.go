@@ -0,0 +1,104 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mah0x211/mixdown/check"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/util"
+)
+
+// load the list of outputs from outdir
+func (b *Builder) readManifest() ([]*Output, error) {
+	var outputs []*Output
+	pathname := filepath.Join(b.OutDir, ManifestFile)
+	if buf, err := ioutil.ReadFile(pathname); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	} else if err = json.Unmarshal(buf, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to load %q: %s", pathname, err)
+	}
+	return outputs, nil
+}
+
+// Check verifies the references of the pages in outdir, and returns an error
+// if broken links, missing anchors or orphaned pages are found
+func (b *Builder) Check() error {
+	logger.Infof("check output directory %q", b.OutDir)
+	if ok, err := util.IsDir(b.OutDir); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("outdir %q is not found", b.OutDir)
+	}
+
+	// outputs of the last build
+	outputs := b.Outputs
+	if outputs == nil {
+		list, err := b.readManifest()
+		if err != nil {
+			return err
+		}
+		outputs = list
+	}
+	origins := make(map[string]*Output)
+	for _, o := range outputs {
+		origins[o.Pathname] = o
+	}
+
+	c, err := check.New(b.OutDir, b.BaseURL, b.Extname)
+	if err != nil {
+		return fmt.Errorf("failed to check.New(): %s", err)
+	}
+	problems, err := c.Run()
+	if err != nil {
+		return fmt.Errorf("failed to Checker.Run(): %s", err)
+	}
+
+	for _, p := range problems {
+		origin := "unknown origin"
+		if o, ok := origins[p.Page]; ok {
+			origin = fmt.Sprintf("target %q, template %q, source %q", o.Target, o.Template, o.Source)
+		}
+		if p.Ref != "" {
+			logger.Errorf("%s %q in %q - %s (%s)", p.Kind, p.Ref, p.Page, p.Reason, origin)
+		} else {
+			logger.Errorf("%s %q - %s (%s)", p.Kind, p.Page, p.Reason, origin)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems", len(problems))
+	}
+	logger.Infof("no problems found")
+
+	return nil
+}
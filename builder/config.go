@@ -0,0 +1,487 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/rex"
+	"gopkg.in/yaml.v3"
+)
+
+// DotDir is the directory of the config file, theme and archetypes
+const DotDir string = ".mixdown/"
+
+// Config is the configuration parameters of the build. the parameters are
+// overridden in order of the config file, the environment variable of the env
+// tag and the command-line parameter. the parameters have no omitempty so that
+// the config file of init lists all of them, and Jobs is 0 by default, that is
+// the number of CPUs, so that the config file does not depend on the machine.
+type Config struct {
+	BaseURL      string `json:"baseURL" yaml:"baseURL" toml:"baseURL" env:"MIXDOWN_BASE_URL"`
+	OutDir       string `json:"outdir" yaml:"outdir" toml:"outdir" env:"MIXDOWN_OUTDIR"`
+	UseEpochname bool   `json:"useEpochname" yaml:"useEpochname" toml:"useEpochname" env:"MIXDOWN_USE_EPOCHNAME"`
+	Extname      string `json:"extname" yaml:"extname" toml:"extname" env:"MIXDOWN_EXTNAME"`
+	NArchive     int    `json:"narchive" yaml:"narchive" toml:"narchive" env:"MIXDOWN_NARCHIVE"`
+	Sitemap      string `json:"sitemap" yaml:"sitemap" toml:"sitemap" env:"MIXDOWN_SITEMAP"`
+	Jobs         int    `json:"jobs" yaml:"jobs" toml:"jobs" env:"MIXDOWN_JOBS"`
+	Backup       string `json:"backup" yaml:"backup" toml:"backup" env:"MIXDOWN_BACKUP"`
+
+	DryRun       bool   `json:"-" yaml:"-" toml:"-" env:"MIXDOWN_DRY_RUN"`
+	Force        bool   `json:"-" yaml:"-" toml:"-" env:"MIXDOWN_FORCE"`
+	ThemeDir     string `json:"-" yaml:"-" toml:"-" env:"MIXDOWN_THEME_DIR"`
+	ArchetypeDir string `json:"-" yaml:"-" toml:"-" env:"MIXDOWN_ARCHETYPE_DIR"`
+	DataDir      string `json:"-" yaml:"-" toml:"-" env:"MIXDOWN_DATA_DIR"`
+
+	// site-level metadata and the free-form parameters for templates
+	Site   SiteConfig             `json:"site" yaml:"site" toml:"site"`
+	Params map[string]interface{} `json:"params" yaml:"params" toml:"params"`
+
+	// output pathnames of the standalone pages by template name
+	Routes map[string]string `json:"routes" yaml:"routes" toml:"routes"`
+
+	// source of the values of the parameters by field name
+	sources map[string]string
+}
+
+// ConfigExts is the list of extensions of the config file in order of
+// precedence
+var ConfigExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// NewConfig returns the configuration with default values
+func NewConfig() *Config {
+	return &Config{
+		BaseURL:      "/",
+		OutDir:       "docs",
+		UseEpochname: false,
+		Extname:      "html",
+		NArchive:     40,
+		Jobs:         0,
+
+		ThemeDir:     filepath.Join(DotDir, "theme"),
+		ArchetypeDir: filepath.Join(DotDir, "archetypes"),
+		DataDir:      filepath.Join(DotDir, "data"),
+
+		Site: SiteConfig{
+			Language: "en",
+			Social:   []Link{},
+		},
+		Params: make(map[string]interface{}),
+		Routes: make(map[string]string),
+	}
+}
+
+// returns the first existing file of basename with ConfigExts, or an empty
+// string if none of them exist
+func findConfigFile(basename string) (string, error) {
+	found := ""
+	for _, ext := range ConfigExts {
+		pathname := basename + ext
+		if _, err := os.Stat(pathname); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		} else if found == "" {
+			found = pathname
+		} else {
+			logger.Warnf("ignore config file %q - %q takes precedence", pathname, found)
+		}
+	}
+	return found, nil
+}
+
+// FindConfigFiles returns the config file and the overlay of env in order
+// of loading. if cfgFile is empty, config file is searched in DotDir. the
+// overlay is the file named <basename>.<env>.<ext> next to the config file,
+// e.g. config.production.json
+func FindConfigFiles(cfgFile, env string) ([]string, error) {
+	var files []string
+	basename := filepath.Join(DotDir, "config")
+	if cfgFile != "" {
+		ext := filepath.Ext(cfgFile)
+		if !isConfigExt(ext) {
+			return nil, fmt.Errorf("unsupported config file %q - extension must be one of %s", cfgFile, strings.Join(ConfigExts, ", "))
+		} else if _, err := os.Stat(cfgFile); err != nil {
+			return nil, err
+		}
+		files = append(files, cfgFile)
+		basename = strings.TrimSuffix(cfgFile, ext)
+	} else if pathname, err := findConfigFile(basename); err != nil {
+		return nil, err
+	} else if pathname != "" {
+		files = append(files, pathname)
+	}
+
+	if env != "" {
+		if env != filepath.Base(env) || strings.HasPrefix(env, ".") {
+			return nil, fmt.Errorf("invalid env %q", env)
+		} else if pathname, err := findConfigFile(basename + "." + env); err != nil {
+			return nil, err
+		} else if pathname == "" {
+			return nil, fmt.Errorf("config file of env %q is not found - %s.%s{%s}", env, basename, env, strings.Join(ConfigExts, ","))
+		} else {
+			files = append(files, pathname)
+		}
+	}
+
+	return files, nil
+}
+
+func isConfigExt(ext string) bool {
+	for _, v := range ConfigExts {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the config file in the format of its extension, the parameters
+// that are not in the file are not changed
+func (c *Config) Load(cfgFile string) error {
+	buf, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load %q: %s", cfgFile, err)
+	}
+
+	logger.Debugf("load config file %q", cfgFile)
+	keys := make(map[string]interface{})
+	if err = unmarshalConfig(cfgFile, buf, c); err != nil {
+		return fmt.Errorf("failed to load %q: %s", cfgFile, err)
+	} else if err = unmarshalConfig(cfgFile, buf, &keys); err != nil {
+		return fmt.Errorf("failed to load %q: %s", cfgFile, err)
+	}
+
+	c.setFileSources(reflect.TypeOf(c).Elem(), "", keys, "file "+cfgFile)
+	return nil
+}
+
+// record src as the source of the parameters of t and its nested structs that
+// are in keys, e.g. "Site" and "Site.Author.Email"
+func (c *Config) setFileSources(t reflect.Type, prefix string, keys map[string]interface{}, src string) {
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("json")
+		v, ok := keys[key]
+		if !ok || key == "-" || key == "" {
+			continue
+		}
+
+		field := prefix + t.Field(i).Name
+		c.SetSource(field, src)
+		if nested, ok := v.(map[string]interface{}); ok && t.Field(i).Type.Kind() == reflect.Struct {
+			c.setFileSources(t.Field(i).Type, field+".", nested, src)
+		}
+	}
+}
+
+// LoadEnv sets the parameters from the environment variables of the env tags
+// that are found by lookup, e.g. MIXDOWN_BASE_URL
+func (c *Config) LoadEnv(lookup func(name string) (string, bool)) error {
+	return c.loadEnv(reflect.ValueOf(c).Elem(), "", lookup)
+}
+
+// set the fields of v and its nested structs from the environment variables
+func (c *Config) loadEnv(v reflect.Value, prefix string, lookup func(name string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := prefix + t.Field(i).Name
+		if f := v.Field(i); f.Kind() == reflect.Struct {
+			if err := c.loadEnv(f, field+".", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		val, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(val)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("error invalid %s %q - must be a boolean", name, val)
+			}
+			f.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("error invalid %s %q - must be an integer", name, val)
+			}
+			f.SetInt(int64(n))
+		}
+		c.SetSource(field, "env "+name)
+	}
+
+	return nil
+}
+
+// SetSource records src as the source of the value of the parameter of the
+// field name
+func (c *Config) SetSource(name, src string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[name] = src
+}
+
+// Source returns the source of the value of the parameter of the field name,
+// it is "default" if the value was not changed
+func (c *Config) Source(name string) string {
+	if src, ok := c.sources[name]; ok {
+		return src
+	}
+	return "default"
+}
+
+// decode buf into v in the format of the extension of cfgFile
+func unmarshalConfig(cfgFile string, buf []byte, v interface{}) error {
+	switch filepath.Ext(cfgFile) {
+	case ".json":
+		return json.Unmarshal(buf, v)
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(buf, v)
+	case ".toml":
+		return toml.Unmarshal(buf, v)
+	default:
+		return fmt.Errorf("extension must be one of %s", strings.Join(ConfigExts, ", "))
+	}
+}
+
+// Marshal returns the encoding of the configuration parameters in the format
+// of the extension of cfgFile
+func (c *Config) Marshal(cfgFile string) ([]byte, error) {
+	switch filepath.Ext(cfgFile) {
+	case ".json":
+		buf, err := json.MarshalIndent(c, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, '\n'), nil
+	case ".yaml", ".yml":
+		return yaml.Marshal(c)
+	case ".toml":
+		var b bytes.Buffer
+		if err := toml.NewEncoder(&b).Encode(c); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported config file %q - extension must be one of %s", cfgFile, strings.Join(ConfigExts, ", "))
+	}
+}
+
+// ConfigError is the problem of the value of a configuration parameter
+type ConfigError struct {
+	Key    string
+	Value  interface{}
+	Source string
+	Reason string
+	Hint   string
+}
+
+func (e *ConfigError) Error() string {
+	msg := fmt.Sprintf("error invalid %s %#v from %s - %s", e.Key, e.Value, e.Source, e.Reason)
+	if e.Hint != "" {
+		msg += " (hint: " + e.Hint + ")"
+	}
+	return msg
+}
+
+// ConfigErrors is the list of all problems of the configuration parameters
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate returns ConfigErrors of all problems of the configuration
+// parameters, or nil if there are no problems
+func (c *Config) Validate() error {
+	var errs ConfigErrors
+	invalid := func(name, key string, value interface{}, reason, hint string) {
+		errs = append(errs, &ConfigError{
+			Key:    key,
+			Value:  value,
+			Source: c.Source(name),
+			Reason: reason,
+			Hint:   hint,
+		})
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil {
+		invalid("BaseURL", "baseURL", c.BaseURL, err.Error(), `e.g. "/" or "/blog/"`)
+	} else if u.Scheme != "" || u.Host != "" || !strings.HasPrefix(c.BaseURL, "/") {
+		invalid("BaseURL", "baseURL", c.BaseURL, "must be an absolute path", `e.g. "/" or "/blog/", use sitemap for the hostname`)
+	} else if u.RawQuery != "" || u.ForceQuery || u.Fragment != "" || strings.ContainsAny(c.BaseURL, "?#") {
+		invalid("BaseURL", "baseURL", c.BaseURL, "must not include the query or fragment", `e.g. "/" or "/blog/"`)
+	} else if path.Clean(c.BaseURL) != strings.TrimSuffix(c.BaseURL, "/") && c.BaseURL != "/" {
+		invalid("BaseURL", "baseURL", c.BaseURL, "must not include empty, '.' or '..' segments", fmt.Sprintf("use %q", path.Clean(c.BaseURL)+"/"))
+	}
+
+	dotdir := filepath.Clean(DotDir)
+	for _, v := range []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"OutDir", "outdir", c.OutDir},
+		{"Backup", "backup", c.Backup},
+	} {
+		if v.value == "" {
+			continue
+		} else if v.value == "~" || strings.HasPrefix(v.value, "~"+string(filepath.Separator)) {
+			invalid(v.name, v.key, v.value, "the leading '~' is not expanded to the home directory", "use the absolute path")
+		} else if pathname := filepath.Clean(v.value); pathname == dotdir || strings.HasPrefix(pathname, dotdir+string(filepath.Separator)) {
+			invalid(v.name, v.key, v.value, fmt.Sprintf("cannot be output to the %q directory", DotDir), "specify a directory outside of "+DotDir)
+		}
+	}
+
+	if !rex.Extname.MatchString(c.Extname) {
+		invalid("Extname", "extname", c.Extname, "extname must be [0-9a-zA-Z_]+", `e.g. "html", without the leading dot`)
+	}
+	if c.NArchive < 1 {
+		invalid("NArchive", "narchive", c.NArchive, "narchive must be greater than 0", "number of articles in an archive page")
+	}
+	if c.Jobs < 0 {
+		invalid("Jobs", "jobs", c.Jobs, "jobs must be greater than or equal to 0", "0 means the number of CPUs")
+	}
+
+	if sitemap := strings.TrimSpace(c.Sitemap); sitemap != "" {
+		if u, err := url.Parse(sitemap); err != nil {
+			invalid("Sitemap", "sitemap", c.Sitemap, err.Error(), `e.g. "https://example.com"`)
+		} else if u.Scheme == "" || u.Host == "" {
+			invalid("Sitemap", "sitemap", c.Sitemap, "sitemap must be fully qualified url", `e.g. "https://example.com"`)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			invalid("Sitemap", "sitemap", c.Sitemap, "scheme must be 'http' or 'https'", `e.g. "https://example.com"`)
+		} else if u.User != nil || u.Path != "" || u.ForceQuery || u.Fragment != "" || strings.HasSuffix(sitemap, "#") {
+			invalid("Sitemap", "sitemap", c.Sitemap, "do not include the userinfo, path, query or fragment", fmt.Sprintf("use %q and baseURL for the path", u.Scheme+"://"+u.Host))
+		}
+	}
+
+	if c.Site.Language != "" && !rex.Language.MatchString(c.Site.Language) {
+		invalid("Site.Language", "site.language", c.Site.Language, "must be a language tag", `e.g. "en" or "ja-JP"`)
+	}
+	if _, err := c.Site.Location(); err != nil {
+		invalid("Site.Timezone", "site.timezone", c.Site.Timezone, err.Error(), `e.g. "UTC" or "Asia/Tokyo", or empty for the local timezone`)
+	}
+	if c.Site.Author.Email != "" {
+		if _, err := mail.ParseAddress(c.Site.Author.Email); err != nil {
+			invalid("Site.Author.Email", "site.author.email", c.Site.Author.Email, err.Error(), `e.g. "alice@example.com"`)
+		}
+	}
+	if c.Site.Author.URL != "" && !isLinkURL(c.Site.Author.URL) {
+		invalid("Site.Author.URL", "site.author.url", c.Site.Author.URL, "must be an absolute url or path", `e.g. "https://example.com/about"`)
+	}
+	for i, link := range c.Site.Social {
+		key := fmt.Sprintf("site.social[%d]", i)
+		if link.Name == "" {
+			invalid("Site.Social", key+".name", link.Name, "must not be empty", `e.g. "github"`)
+		}
+		if !isLinkURL(link.URL) {
+			invalid("Site.Social", key+".url", link.URL, "must be an absolute url or path", `e.g. "https://github.com/mah0x211"`)
+		}
+	}
+
+	names := make([]string, 0, len(c.Routes))
+	for name := range c.Routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key, route := "routes."+name, c.Routes[name]
+		// the route of the output of the extension is declared as "name.ext"
+		if isPageTemplate(name) || isPageTemplate(strings.TrimSuffix(name, path.Ext(name))) {
+			invalid("Routes", key, route, fmt.Sprintf("template %q is not a standalone page", name), "remove the route of "+name)
+		} else if route == "" || path.IsAbs(route) || path.Clean(route) != route || route == "." || route == ".." || strings.HasPrefix(route, "../") {
+			invalid("Routes", key, route, "must be a clean relative path in outdir", `e.g. "search/index.html"`)
+		}
+	}
+
+	if c.ThemeDir == "" {
+		invalid("ThemeDir", "themeDir", c.ThemeDir, "must not be empty", "set MIXDOWN_THEME_DIR or unset it to use "+filepath.Join(DotDir, "theme"))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// returns true if s is an absolute url or an absolute path
+func isLinkURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	} else if u.Scheme != "" {
+		return u.Opaque != "" || u.Host != ""
+	}
+	return strings.HasPrefix(u.Path, "/")
+}
+
+// Verify validates and normalizes the configuration parameters
+func (c *Config) Verify() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	// the templates join the paths to BaseURL, e.g. {{.BaseURL}}archive/
+	if !strings.HasSuffix(c.BaseURL, "/") {
+		c.BaseURL += "/"
+	}
+	c.OutDir = filepath.Join(c.OutDir)
+	if c.Backup != "" {
+		c.Backup = filepath.Join(c.Backup)
+	}
+	c.Sitemap = strings.TrimSpace(c.Sitemap)
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package builder
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// write the files into the temporary directory and returns their pathnames
+func writeTestConfigs(t *testing.T, files ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	pathnames := make([]string, 0, len(files)/2)
+	for i := 0; i < len(files); i += 2 {
+		pathname := filepath.Join(dir, files[i])
+		if err := ioutil.WriteFile(pathname, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pathnames = append(pathnames, pathname)
+	}
+	return pathnames
+}
+
+// returns the lookup function of the environment variables of env
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestConfigLoad(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		text string
+	}{
+		{"config.json", `{"baseURL": "/blog/", "jobs": 2, "site": {"title": "x"}}`},
+		{"config.yaml", "baseURL: /blog/\njobs: 2\nsite:\n  title: x\n"},
+		{"config.yml", "baseURL: /blog/\njobs: 2\nsite:\n  title: x\n"},
+		{"config.toml", "baseURL = \"/blog/\"\njobs = 2\n[site]\ntitle = \"x\"\n"},
+	} {
+		pathname := writeTestConfigs(t, c.name, c.text)[0]
+		cfg := NewConfig()
+		if err := cfg.Load(pathname); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if cfg.BaseURL != "/blog/" || cfg.Jobs != 2 || cfg.Site.Title != "x" {
+			t.Errorf("%s: baseURL=%q jobs=%d site.title=%q", c.name, cfg.BaseURL, cfg.Jobs, cfg.Site.Title)
+		}
+		// the parameters that are not in the file keep the default values
+		if cfg.Extname != "html" || cfg.NArchive != 40 || cfg.Site.Language != "en" {
+			t.Errorf("%s: extname=%q narchive=%d site.language=%q", c.name, cfg.Extname, cfg.NArchive, cfg.Site.Language)
+		}
+		for field, want := range map[string]string{
+			"BaseURL": "file " + pathname,
+			"Jobs":    "file " + pathname,
+			"Site":    "file " + pathname,
+			"Extname": "default",
+		} {
+			if got := cfg.Source(field); got != want {
+				t.Errorf("%s: Source(%q) = %q, want %q", c.name, field, got, want)
+			}
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		text string
+	}{
+		{"config.json", `{"baseURL": `},
+		{"config.yaml", "baseURL: [\n"},
+		{"config.toml", "baseURL = \n"},
+		{"config.ini", "baseURL=/"},
+	} {
+		pathname := writeTestConfigs(t, c.name, c.text)[0]
+		if err := NewConfig().Load(pathname); err == nil || !strings.Contains(err.Error(), pathname) {
+			t.Errorf("Load(%q) returns %v", c.name, err)
+		}
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	for _, c := range []struct {
+		env   string
+		value string
+		field string
+		get   func(*Config) interface{}
+		want  interface{}
+	}{
+		{"MIXDOWN_BASE_URL", "/env/", "BaseURL", func(c *Config) interface{} { return c.BaseURL }, "/env/"},
+		{"MIXDOWN_USE_EPOCHNAME", "true", "UseEpochname", func(c *Config) interface{} { return c.UseEpochname }, true},
+		{"MIXDOWN_NARCHIVE", "10", "NArchive", func(c *Config) interface{} { return c.NArchive }, 10},
+		{"MIXDOWN_DRY_RUN", "1", "DryRun", func(c *Config) interface{} { return c.DryRun }, true},
+		{"MIXDOWN_THEME_DIR", "theme", "ThemeDir", func(c *Config) interface{} { return c.ThemeDir }, "theme"},
+		{"MIXDOWN_SITE_TITLE", "title", "Site.Title", func(c *Config) interface{} { return c.Site.Title }, "title"},
+		{"MIXDOWN_SITE_AUTHOR_EMAIL", "a@example.com", "Site.Author.Email", func(c *Config) interface{} { return c.Site.Author.Email }, "a@example.com"},
+	} {
+		cfg := NewConfig()
+		if err := cfg.LoadEnv(lookupEnv(map[string]string{c.env: c.value})); err != nil {
+			t.Fatalf("%s: %s", c.env, err)
+		} else if got := c.get(cfg); got != c.want {
+			t.Errorf("%s = %v, want %v", c.env, got, c.want)
+		} else if src := cfg.Source(c.field); src != "env "+c.env {
+			t.Errorf("%s: Source(%q) = %q", c.env, c.field, src)
+		}
+	}
+
+	for env, value := range map[string]string{
+		"MIXDOWN_JOBS":          "many",
+		"MIXDOWN_USE_EPOCHNAME": "yes please",
+	} {
+		err := NewConfig().LoadEnv(lookupEnv(map[string]string{env: value}))
+		if err == nil || !strings.Contains(err.Error(), env) {
+			t.Errorf("LoadEnv() with %s=%q returns %v", env, value, err)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	files := writeTestConfigs(t,
+		"config.json", `{"baseURL": "/file/", "outdir": "file", "jobs": 1, "narchive": 5}`,
+		"config.production.yaml", "outdir: overlay\njobs: 2\n",
+	)
+	cfg := NewConfig()
+	for _, pathname := range files {
+		if err := cfg.Load(pathname); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cfg.LoadEnv(lookupEnv(map[string]string{
+		"MIXDOWN_JOBS":     "3",
+		"MIXDOWN_NARCHIVE": "6",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	// the command-line parameter is applied last
+	cfg.NArchive = 7
+	cfg.SetSource("NArchive", "flag -narchive")
+
+	for _, c := range []struct {
+		field string
+		got   interface{}
+		want  interface{}
+		src   string
+	}{
+		{"BaseURL", cfg.BaseURL, "/file/", "file " + files[0]},
+		{"OutDir", cfg.OutDir, "overlay", "file " + files[1]},
+		{"Jobs", cfg.Jobs, 3, "env MIXDOWN_JOBS"},
+		{"NArchive", cfg.NArchive, 7, "flag -narchive"},
+		{"Extname", cfg.Extname, "html", "default"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+		} else if src := cfg.Source(c.field); src != c.src {
+			t.Errorf("Source(%q) = %q, want %q", c.field, src, c.src)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := NewConfig().Validate(); err != nil {
+		t.Fatalf("Validate() of the default config returns %v", err)
+	}
+
+	for _, c := range []struct {
+		set  func(*Config)
+		key  string
+		hint string
+	}{
+		{func(c *Config) { c.BaseURL = "blog/" }, "baseURL", "use sitemap for the hostname"},
+		{func(c *Config) { c.BaseURL = "https://example.com/" }, "baseURL", "use sitemap for the hostname"},
+		{func(c *Config) { c.BaseURL = "/blog/?a=1" }, "baseURL", `"/blog/"`},
+		{func(c *Config) { c.BaseURL = "/a/../b/" }, "baseURL", `use "/b/"`},
+		{func(c *Config) { c.OutDir = ".mixdown/out" }, "outdir", "outside of"},
+		{func(c *Config) { c.Backup = ".mixdown" }, "backup", "outside of"},
+		{func(c *Config) { c.OutDir = "~" }, "outdir", "absolute path"},
+		{func(c *Config) { c.Backup = filepath.Join("~", "backup") }, "backup", "absolute path"},
+		{func(c *Config) { c.Extname = ".html" }, "extname", "without the leading dot"},
+		{func(c *Config) { c.NArchive = 0 }, "narchive", "number of articles"},
+		{func(c *Config) { c.Jobs = -1 }, "jobs", "number of CPUs"},
+		{func(c *Config) { c.Sitemap = "example.com" }, "sitemap", "https://example.com"},
+		{func(c *Config) { c.Sitemap = "ftp://example.com" }, "sitemap", "https://example.com"},
+		{func(c *Config) { c.Sitemap = "https://example.com/blog" }, "sitemap", `use "https://example.com"`},
+		{func(c *Config) { c.Site.Language = "en us" }, "site.language", "ja-JP"},
+		{func(c *Config) { c.Site.Timezone = "Mars/Olympus" }, "site.timezone", "Asia/Tokyo"},
+		{func(c *Config) { c.Site.Author.Email = "alice" }, "site.author.email", "alice@example.com"},
+		{func(c *Config) { c.Site.Social = []Link{{Name: "x", URL: "example.com"}} }, "site.social[0].url", "https://"},
+		{func(c *Config) { c.Routes = map[string]string{"home": "top.html"} }, "routes.home", "remove the route"},
+		{func(c *Config) { c.Routes = map[string]string{"tag.xml": "tag.xml"} }, "routes.tag.xml", "remove the route"},
+		{func(c *Config) { c.Routes = map[string]string{"search": "../search.html"} }, "routes.search", "search/index.html"},
+		{func(c *Config) { c.ThemeDir = "" }, "themeDir", "MIXDOWN_THEME_DIR"},
+	} {
+		cfg := NewConfig()
+		c.set(cfg)
+		err := cfg.Validate()
+		errs, ok := err.(ConfigErrors)
+		if !ok || len(errs) != 1 {
+			t.Errorf("Validate() of %s returns %#v", c.key, err)
+			continue
+		}
+		if errs[0].Key != c.key || errs[0].Source != "default" || !strings.Contains(errs[0].Hint, c.hint) {
+			t.Errorf("Validate() of %s returns %s", c.key, errs[0])
+		}
+	}
+
+	// all problems are reported with their sources
+	cfg := NewConfig()
+	cfg.Extname = ".html"
+	cfg.SetSource("Extname", "flag -extname")
+	cfg.Jobs = -1
+	cfg.SetSource("Jobs", "env MIXDOWN_JOBS")
+	errs, ok := cfg.Validate().(ConfigErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Validate() returns %v", errs)
+	}
+	for i, want := range []string{
+		`error invalid extname ".html" from flag -extname - extname must be [0-9a-zA-Z_]+ (hint: e.g. "html", without the leading dot)`,
+		`error invalid jobs -1 from env MIXDOWN_JOBS - jobs must be greater than or equal to 0 (hint: 0 means the number of CPUs)`,
+	} {
+		if got := errs[i].Error(); got != want {
+			t.Errorf("errs[%d] = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestConfigValidateFileSource(t *testing.T) {
+	// the nested parameters report the file they came from
+	for _, c := range []struct {
+		name string
+		text string
+	}{
+		{"config.json", `{"site": {"language": "x_y", "author": {"email": "alice"}}}`},
+		{"config.yaml", "site:\n  language: x_y\n  author:\n    email: alice\n"},
+		{"config.toml", "[site]\nlanguage = \"x_y\"\n[site.author]\nemail = \"alice\"\n"},
+	} {
+		pathname := writeTestConfigs(t, c.name, c.text)[0]
+		cfg := NewConfig()
+		if err := cfg.Load(pathname); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		errs, ok := cfg.Validate().(ConfigErrors)
+		if !ok || len(errs) != 2 {
+			t.Fatalf("%s: Validate() returns %v", c.name, errs)
+		}
+		for i, key := range []string{"site.language", "site.author.email"} {
+			if errs[i].Key != key || errs[i].Source != "file "+pathname {
+				t.Errorf("%s: errs[%d] = %s", c.name, i, errs[i])
+			}
+		}
+		for field, want := range map[string]string{
+			"Site":              "file " + pathname,
+			"Site.Language":     "file " + pathname,
+			"Site.Author.Email": "file " + pathname,
+			"Site.Title":        "default",
+			"Site.Author.Name":  "default",
+		} {
+			if got := cfg.Source(field); got != want {
+				t.Errorf("%s: Source(%q) = %q, want %q", c.name, field, got, want)
+			}
+		}
+	}
+}
+
+func TestConfigVerify(t *testing.T) {
+	cfg := NewConfig()
+	cfg.BaseURL = "/blog"
+	cfg.OutDir = "out/"
+	cfg.Sitemap = " https://example.com "
+	if err := cfg.Verify(); err != nil {
+		t.Fatal(err)
+	} else if cfg.BaseURL != "/blog/" || cfg.OutDir != "out" || cfg.Sitemap != "https://example.com" {
+		t.Errorf("Verify() normalizes to baseURL=%q outdir=%q sitemap=%q", cfg.BaseURL, cfg.OutDir, cfg.Sitemap)
+	}
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/util"
+	"gopkg.in/yaml.v3"
+)
+
+// decode the data file in the format of its extension. the records of csv
+// file are decoded into the list of maps keyed by the header record.
+func decodeDataFile(pathname string) (interface{}, error) {
+	buf, err := ioutil.ReadFile(pathname)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	switch filepath.Ext(pathname) {
+	case ".json":
+		err = json.Unmarshal(buf, &v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &v)
+	case ".toml":
+		var m map[string]interface{}
+		err = toml.Unmarshal(buf, &m)
+		v = m
+	case ".csv":
+		var records [][]string
+		if records, err = csv.NewReader(bytes.NewReader(buf)).ReadAll(); err == nil {
+			list := make([]map[string]string, 0, len(records))
+			for i := 1; i < len(records); i++ {
+				row := make(map[string]string, len(records[0]))
+				for j, name := range records[0] {
+					row[name] = records[i][j]
+				}
+				list = append(list, row)
+			}
+			v = list
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %q: %s", pathname, err)
+	}
+
+	return v, nil
+}
+
+// returns true if ext is the extension of data files
+func isDataExt(ext string) bool {
+	switch ext {
+	case ".json", ".yaml", ".yml", ".toml", ".csv":
+		return true
+	}
+	return false
+}
+
+// load every data file under datadir into the nested map keyed by the
+// pathname without extension, e.g. data/menu/main.yaml is .menu.main
+func loadData(datadir string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	if ok, err := util.IsDir(datadir); err != nil {
+		return nil, err
+	} else if !ok {
+		return data, nil
+	}
+
+	err := filepath.Walk(datadir, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if pathname == datadir {
+			return nil
+		} else if strings.HasPrefix(info.Name(), ".") {
+			// skip dotfiles
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if info.IsDir() || !isDataExt(filepath.Ext(pathname)) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(datadir, pathname)
+		if err != nil {
+			return err
+		}
+		v, err := decodeDataFile(pathname)
+		if err != nil {
+			return err
+		}
+		logger.Debugf("data %q", pathname)
+
+		// create the nested maps of directories. directories are walked before
+		// the files of the same name with extension.
+		keys := strings.Split(strings.TrimSuffix(rel, filepath.Ext(rel)), string(filepath.Separator))
+		m := data
+		for _, key := range keys[:len(keys)-1] {
+			if next, ok := m[key].(map[string]interface{}); ok {
+				m = next
+			} else {
+				next = make(map[string]interface{})
+				m[key] = next
+				m = next
+			}
+		}
+		key := keys[len(keys)-1]
+		if _, ok := m[key]; ok {
+			return fmt.Errorf("%q conflicts with the data of %q", pathname, strings.Join(keys, "/"))
+		}
+		m[key] = v
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
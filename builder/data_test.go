@@ -0,0 +1,110 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// write the files of the pathnames and contents into the temporary directory
+// and returns it
+func writeTestDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		pathname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		} else if err = ioutil.WriteFile(pathname, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDecodeDataFile(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		text string
+		want interface{}
+	}{
+		{"a.json", `{"name": "x", "list": [1, 2]}`, map[string]interface{}{"name": "x", "list": []interface{}{1.0, 2.0}}},
+		{"a.yaml", "name: x\nlist: [1, 2]\n", map[string]interface{}{"name": "x", "list": []interface{}{1, 2}}},
+		{"a.yml", "- x\n- y\n", []interface{}{"x", "y"}},
+		{"a.toml", "name = \"x\"\nlist = [1, 2]\n", map[string]interface{}{"name": "x", "list": []interface{}{int64(1), int64(2)}}},
+		{"a.csv", "name,url\nx,/x\n\"y, z\",/y\n", []map[string]string{{"name": "x", "url": "/x"}, {"name": "y, z", "url": "/y"}}},
+		{"b.csv", "name,url\n", []map[string]string{}},
+	} {
+		dir := writeTestDir(t, map[string]string{c.name: c.text})
+		got, err := decodeDataFile(filepath.Join(dir, c.name))
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		} else if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s = %#v, want %#v", c.name, got, c.want)
+		}
+	}
+
+	for name, text := range map[string]string{
+		"a.json": `{"name": `,
+		"a.yaml": "name: [\n",
+		"a.toml": "name = \n",
+		"a.csv":  "name,url\nx\n",
+	} {
+		pathname := filepath.Join(writeTestDir(t, map[string]string{name: text}), name)
+		if _, err := decodeDataFile(pathname); err == nil || !strings.Contains(err.Error(), pathname) {
+			t.Errorf("decodeDataFile(%q) returns %v", name, err)
+		}
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"site.json":             `{"title": "x"}`,
+		"menu/main.yaml":        "- home\n",
+		"menu/footer/links.csv": "name\nabout\n",
+		"menu/README.md":        "not data",
+		".hidden.json":          `{}`,
+		".git/config.json":      `{}`,
+	})
+	data, err := loadData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"site": map[string]interface{}{"title": "x"},
+		"menu": map[string]interface{}{
+			"main": []interface{}{"home"},
+			"footer": map[string]interface{}{
+				"links": []map[string]string{{"name": "about"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("loadData() = %#v, want %#v", data, want)
+	}
+
+	// missing directory
+	if data, err = loadData(filepath.Join(dir, "missing")); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 0 {
+		t.Errorf("loadData() of missing directory = %v", data)
+	}
+
+	// the keys of the data must be unique
+	for _, files := range []map[string]string{
+		{"menu.json": `{}`, "menu.yaml": "a: 1\n"},
+		{"menu.json": `{}`, "menu/main.yaml": "a: 1\n"},
+	} {
+		if _, err = loadData(writeTestDir(t, files)); err == nil || !strings.Contains(err.Error(), `conflicts with the data of "menu"`) {
+			t.Errorf("loadData() of %v returns %v", files, err)
+		}
+	}
+
+	// the error of the data file
+	if _, err = loadData(writeTestDir(t, map[string]string{"a/b.json": `{`})); err == nil {
+		t.Error("loadData() of invalid data file returns no error")
+	}
+}
@@ -0,0 +1,192 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mah0x211/mixdown/util"
+)
+
+// absolute pathname with symbolic links resolved as far as they exist
+func realpath(pathname string) (string, error) {
+	pathname, err := filepath.Abs(pathname)
+	if err != nil {
+		return "", err
+	}
+
+	// resolve the existing ancestor directory
+	var rest []string
+	for {
+		if resolved, err := filepath.EvalSymlinks(pathname); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(pathname)
+		if parent == pathname {
+			return filepath.Join(append([]string{pathname}, rest...)...), nil
+		}
+		rest = append([]string{filepath.Base(pathname)}, rest...)
+		pathname = parent
+	}
+}
+
+// contains returns true if dir is equal to pathname or its ancestor
+func contains(dir, pathname string) bool {
+	rel, err := filepath.Rel(dir, pathname)
+	return err == nil && rel != ".." &&
+		!strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// verifyRemovable verifies that the directory can be removed safely. it
+// refuses the directory that contains the repository root or the .mixdown
+// directory, and the non-empty directory that was not created by mixdown
+// unless forced.
+func (b *Builder) verifyRemovable(name, dirname string) error {
+	realdir, err := realpath(dirname)
+	if err != nil {
+		return err
+	}
+
+	// repository root or working directory
+	root := "."
+	if out, err := util.ExecCommand("git", "rev-parse", "--show-toplevel"); err == nil {
+		root = string(out)
+	}
+	for _, dir := range []string{root, DotDir} {
+		if pathname, err := realpath(dir); err != nil {
+			return err
+		} else if contains(realdir, pathname) || contains(pathname, realdir) && dir != root {
+			return fmt.Errorf("%s %q must not contain or be inside of %q", name, dirname, pathname)
+		}
+	}
+
+	// verify existing directory
+	if info, err := os.Stat(dirname); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s %q already exists as a non-directory", name, dirname)
+	} else if b.Force {
+		return nil
+	}
+
+	if finfos, err := ioutil.ReadDir(dirname); err != nil {
+		return err
+	} else if len(finfos) == 0 {
+		return nil
+	} else if _, err = os.Stat(filepath.Join(dirname, ManifestFile)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s %q is not empty and has no %q file created by mixdown - use -force option to overwrite it", name, dirname, ManifestFile)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// siblingDir returns the pathname of the hidden directory next to outdir
+func (b *Builder) siblingDir(suffix string) string {
+	return filepath.Join(filepath.Dir(b.OutDir), "."+filepath.Base(b.OutDir)+"."+suffix)
+}
+
+// verifyOutDir verifies that outdir, the staging directory and the backup
+// directory can be replaced safely
+func (b *Builder) verifyOutDir() error {
+	dirs := []string{"outdir", b.OutDir, "staging", b.siblingDir("staging")}
+	if b.Backup == "" {
+		dirs = append(dirs, "previous outdir", b.siblingDir("old"))
+	} else {
+		dirs = append(dirs, "backup", b.Backup)
+		if outdir, err := realpath(b.OutDir); err != nil {
+			return err
+		} else if backup, err := realpath(b.Backup); err != nil {
+			return err
+		} else if contains(outdir, backup) || contains(backup, outdir) {
+			return fmt.Errorf("backup %q must not contain or be inside of outdir %q", b.Backup, b.OutDir)
+		}
+	}
+
+	for i := 0; i < len(dirs); i += 2 {
+		if err := b.verifyRemovable(dirs[i], dirs[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createStagingDir creates the staging directory next to outdir to build the
+// site without breaking the current outdir
+func (b *Builder) createStagingDir() (string, error) {
+	staging := b.siblingDir("staging")
+	if err := os.RemoveAll(staging); err != nil {
+		return "", fmt.Errorf("failed to os.RemoveAll(): %s", err)
+	} else if err = util.Mkdir(staging); err != nil {
+		return "", fmt.Errorf("failed to util.Mkdir(): %s", err)
+	}
+	return staging, nil
+}
+
+// swapOutDir replaces outdir with the staging directory by renaming them.
+// the previous outdir is kept in the backup directory if specified.
+func (b *Builder) swapOutDir(staging string) error {
+	old := b.Backup
+	if old == "" {
+		old = b.siblingDir("old")
+	} else if err := util.Mkdir(filepath.Dir(old)); err != nil {
+		return fmt.Errorf("failed to util.Mkdir(): %s", err)
+	}
+
+	// move the previous outdir out of the way
+	if err := os.RemoveAll(old); err != nil {
+		return fmt.Errorf("failed to os.RemoveAll(): %s", err)
+	} else if err = os.Rename(b.OutDir, old); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to os.Rename(): %s", err)
+		}
+		old = ""
+	}
+
+	if err := os.Rename(staging, b.OutDir); err != nil {
+		// restore the previous outdir
+		if old != "" {
+			os.Rename(old, b.OutDir)
+		}
+		return fmt.Errorf("failed to os.Rename(): %s", err)
+	} else if old != "" && b.Backup == "" {
+		if err = os.RemoveAll(old); err != nil {
+			return fmt.Errorf("failed to os.RemoveAll(): %s", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mah0x211/mixdown/util"
+)
+
+// create the git repository in $HOME/repo of the temporary home directory and
+// change the working directory to it. it returns the home directory.
+func chdirTestRepo(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	repo := filepath.Join(home, "repo")
+	if err := os.Mkdir(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	} else if err = os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err = util.ExecCommand("git", "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// write the files of the pathnames with their names as contents
+func writeTestFiles(t *testing.T, pathnames ...string) {
+	t.Helper()
+	for _, pathname := range pathnames {
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		} else if err = ioutil.WriteFile(pathname, []byte(pathname), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyOutDir(t *testing.T) {
+	home := chdirTestRepo(t)
+	writeTestFiles(t,
+		filepath.Join("nomanifest", "index.html"),
+		filepath.Join("built", "index.html"),
+		filepath.Join("built", ManifestFile),
+		"file",
+	)
+	if err := os.Mkdir("empty", 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		outdir string
+		backup string
+		force  bool
+		errmsg string
+	}{
+		// the directories that must not be removed
+		{outdir: ".", errmsg: "must not contain or be inside of"},
+		{outdir: wd, errmsg: "must not contain or be inside of"},
+		{outdir: home, errmsg: "must not contain or be inside of"},
+		{outdir: filepath.Join(wd, "..", "repo"), errmsg: "must not contain or be inside of"},
+		{outdir: ".mixdown", errmsg: "must not contain or be inside of"},
+		{outdir: filepath.Join(".mixdown", "theme"), errmsg: "must not contain or be inside of"},
+		{outdir: "file", errmsg: "as a non-directory"},
+		{outdir: "nomanifest", errmsg: "use -force option"},
+		{outdir: "nomanifest", force: true},
+		{outdir: "built"},
+		{outdir: "empty"},
+		{outdir: "missing"},
+		{outdir: "built", backup: filepath.Join("built", "old"), errmsg: "must not contain or be inside of outdir"},
+		{outdir: "built", backup: ".", errmsg: "must not contain or be inside of outdir"},
+		{outdir: "built", backup: "nomanifest", errmsg: "use -force option"},
+		{outdir: "built", backup: "backup"},
+	} {
+		b := &Builder{Config: *NewConfig()}
+		b.OutDir, b.Backup, b.Force = c.outdir, c.backup, c.force
+		err := b.verifyOutDir()
+		if c.errmsg == "" && err != nil {
+			t.Errorf("verifyOutDir() of outdir=%q backup=%q returns %s", c.outdir, c.backup, err)
+		} else if c.errmsg != "" && (err == nil || !strings.Contains(err.Error(), c.errmsg)) {
+			t.Errorf("verifyOutDir() of outdir=%q backup=%q returns %v, want %q", c.outdir, c.backup, err, c.errmsg)
+		}
+	}
+
+	// the staging directory of mixdown must not be overwritten by the user's
+	writeTestFiles(t, filepath.Join(".built.staging", "index.html"))
+	b := &Builder{Config: *NewConfig()}
+	b.OutDir = "built"
+	if err := b.verifyOutDir(); err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("verifyOutDir() with the staging directory of the user returns %v", err)
+	}
+}
+
+func TestSwapOutDir(t *testing.T) {
+	chdirTestRepo(t)
+
+	for _, backup := range []string{"", filepath.Join("backups", "site")} {
+		os.RemoveAll("site")
+		writeTestFiles(t,
+			filepath.Join("site", "old.html"),
+			filepath.Join("site", ManifestFile),
+		)
+		b := &Builder{Config: *NewConfig()}
+		b.OutDir, b.Backup = "site", backup
+		if err := b.verifyOutDir(); err != nil {
+			t.Fatal(err)
+		}
+		staging, err := b.createStagingDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFiles(t, filepath.Join(staging, "new.html"))
+		if err = b.swapOutDir(staging); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = os.Stat(filepath.Join("site", "new.html")); err != nil {
+			t.Errorf("outdir is not replaced: %s", err)
+		} else if _, err = os.Stat(filepath.Join("site", "old.html")); err == nil {
+			t.Error("outdir has the previous file")
+		} else if _, err = os.Stat(staging); err == nil {
+			t.Error("staging directory remains")
+		}
+
+		if backup == "" {
+			if _, err = os.Stat(b.siblingDir("old")); err == nil {
+				t.Error("previous outdir remains without backup")
+			}
+		} else if _, err = os.Stat(filepath.Join(backup, "old.html")); err != nil {
+			t.Errorf("previous outdir is not kept in backup: %s", err)
+		}
+	}
+
+	// outdir is created by the first build
+	b := &Builder{Config: *NewConfig()}
+	b.OutDir = "first"
+	staging, err := b.createStagingDir()
+	if err != nil {
+		t.Fatal(err)
+	} else if err = b.swapOutDir(staging); err != nil {
+		t.Fatal(err)
+	} else if ok, err := util.IsDir("first"); err != nil || !ok {
+		t.Errorf("outdir is not created: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/mah0x211/mixdown/util"
+)
+
+// plan holds the checksums of files that would be written in dry-run mode
+type plan struct {
+	mu        sync.Mutex
+	checksums map[string]string
+}
+
+func newPlan() *plan {
+	return &plan{
+		checksums: make(map[string]string),
+	}
+}
+
+// add the checksum of data that would be written into pathname
+func (p *plan) add(pathname string, data []byte) {
+	chksum := util.GenChecksum(data)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checksums[pathname] = chksum
+}
+
+// list existing files in outdir except the manifest
+func (b *Builder) existingFiles() (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.Walk(b.OutDir, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !info.IsDir() {
+			rel, err := filepath.Rel(b.OutDir, pathname)
+			if err != nil {
+				return err
+			} else if rel != ManifestFile {
+				files[rel] = true
+			}
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// PlanEntry is the representation of a file in the plan of the dry-run build
+type PlanEntry struct {
+	// Status is one of "add", "change", "unchanged" or "delete", or empty if
+	// outdir does not exist
+	Status   string
+	Target   string
+	Pathname string
+}
+
+// list every output with its target, and which files would be added,
+// changed or deleted if outdir exists
+func (b *Builder) makePlan() ([]*PlanEntry, error) {
+	existing := map[string]bool(nil)
+	if ok, err := util.IsDir(b.OutDir); err != nil {
+		return nil, err
+	} else if ok {
+		if existing, err = b.existingFiles(); err != nil {
+			return nil, fmt.Errorf("failed to existingFiles(): %s", err)
+		}
+	}
+
+	entries := make([]*PlanEntry, 0, len(b.Outputs))
+	if existing == nil {
+		for _, o := range b.Outputs {
+			entries = append(entries, &PlanEntry{
+				Target:   o.Target,
+				Pathname: o.Pathname,
+			})
+		}
+		return entries, nil
+	}
+
+	for _, o := range b.Outputs {
+		status := "unchanged"
+		if !existing[o.Pathname] {
+			status = "add"
+		} else if buf, err := ioutil.ReadFile(filepath.Join(b.OutDir, o.Pathname)); err != nil {
+			return nil, err
+		} else if util.GenChecksum(buf) != b.plan.checksums[filepath.Join(b.OutDir, o.Pathname)] {
+			status = "change"
+		}
+		delete(existing, o.Pathname)
+		entries = append(entries, &PlanEntry{
+			Status:   status,
+			Target:   o.Target,
+			Pathname: o.Pathname,
+		})
+	}
+
+	// files that are not generated
+	deleted := make([]string, 0, len(existing))
+	for pathname := range existing {
+		deleted = append(deleted, pathname)
+	}
+	sort.Strings(deleted)
+	for _, pathname := range deleted {
+		entries = append(entries, &PlanEntry{
+			Status:   "delete",
+			Pathname: pathname,
+		})
+	}
+
+	return entries, nil
+}
+
+// WritePlan writes the plan of the dry-run build into w. it writes nothing if
+// the report is not of the dry-run build.
+func (r *Report) WritePlan(w io.Writer) error {
+	if !r.DryRun {
+		return nil
+	}
+
+	// outdir does not exist
+	if len(r.Plan) == 0 || r.Plan[0].Status == "" {
+		if _, err := fmt.Fprintf(w, "plan of %d files into %q\n", len(r.Plan), r.OutDir); err != nil {
+			return err
+		}
+		for _, e := range r.Plan {
+			if _, err := fmt.Fprintf(w, "  %-9s %s\n", e.Target, e.Pathname); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	nfile := 0
+	nstatus := make(map[string]int)
+	for _, e := range r.Plan {
+		if e.Status != "delete" {
+			nfile++
+		}
+		nstatus[e.Status]++
+	}
+	if _, err := fmt.Fprintf(w, "plan of %d files into existing %q\n", nfile, r.OutDir); err != nil {
+		return err
+	}
+	for _, e := range r.Plan {
+		if _, err := fmt.Fprintf(w, "  %-9s %-9s %s\n", e.Status, e.Target, e.Pathname); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d changed, %d deleted\n", nstatus["add"], nstatus["change"], nstatus["delete"])
+	return err
+}
@@ -0,0 +1,96 @@
+package builder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakePlan(t *testing.T) {
+	outdir := writeTestDir(t, map[string]string{
+		ManifestFile:                      `[]`,
+		"index.html":                      "home",
+		"about.html":                      "old about",
+		filepath.Join("2018", "old.html"): "old",
+	})
+
+	b := &Builder{Config: *NewConfig()}
+	b.OutDir = outdir
+	b.DryRun = true
+	b.plan = newPlan()
+	for pathname, data := range map[string]string{
+		"index.html": "home",
+		"about.html": "new about",
+		"feed.xml":   "feed",
+	} {
+		b.plan.add(filepath.Join(outdir, pathname), []byte(data))
+	}
+	b.Outputs = []*Output{
+		{Pathname: "index.html", Target: "home"},
+		{Pathname: "about.html", Target: "page"},
+		{Pathname: "feed.xml", Target: "page"},
+	}
+
+	entries, err := b.makePlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	for _, e := range entries {
+		list = append(list, e.Status+" "+e.Target+" "+filepath.ToSlash(e.Pathname))
+	}
+	want := []string{
+		"unchanged home index.html",
+		"change page about.html",
+		"add page feed.xml",
+		"delete  2018/old.html",
+	}
+	if strings.Join(list, "\n") != strings.Join(want, "\n") {
+		t.Errorf("makePlan() =\n%s\nwant\n%s", strings.Join(list, "\n"), strings.Join(want, "\n"))
+	}
+
+	var w strings.Builder
+	report := &Report{OutDir: "docs", DryRun: true, Plan: entries}
+	if err = report.WritePlan(&w); err != nil {
+		t.Fatal(err)
+	} else if want := `plan of 3 files into existing "docs"
+  unchanged home      index.html
+  change    page      about.html
+  add       page      feed.xml
+  delete              ` + filepath.Join("2018", "old.html") + `
+1 added, 1 changed, 1 deleted
+`; w.String() != want {
+		t.Errorf("WritePlan() =\n%s\nwant\n%s", w.String(), want)
+	}
+
+	// outdir does not exist
+	b.OutDir = filepath.Join(outdir, "missing")
+	if entries, err = b.makePlan(); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Status != "" {
+			t.Errorf("status of %q = %q", e.Pathname, e.Status)
+		}
+	}
+	w.Reset()
+	report = &Report{OutDir: "docs", DryRun: true, Plan: entries}
+	if err = report.WritePlan(&w); err != nil {
+		t.Fatal(err)
+	} else if want := `plan of 3 files into "docs"
+  home      index.html
+  page      about.html
+  page      feed.xml
+`; w.String() != want {
+		t.Errorf("WritePlan() =\n%s\nwant\n%s", w.String(), want)
+	}
+
+	// nothing is written without dry-run
+	w.Reset()
+	report.DryRun = false
+	if err = report.WritePlan(&w); err != nil {
+		t.Fatal(err)
+	} else if w.Len() != 0 {
+		t.Errorf("WritePlan() without dry-run writes %q", w.String())
+	}
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package builder
+
+import (
+	"time"
+)
+
+// SiteConfig is the metadata of the site in the site section of the config
+// file
+type SiteConfig struct {
+	Title       string `json:"title" yaml:"title" toml:"title" env:"MIXDOWN_SITE_TITLE"`
+	Description string `json:"description" yaml:"description" toml:"description" env:"MIXDOWN_SITE_DESCRIPTION"`
+	Language    string `json:"language" yaml:"language" toml:"language" env:"MIXDOWN_SITE_LANGUAGE"`
+	Timezone    string `json:"timezone" yaml:"timezone" toml:"timezone" env:"MIXDOWN_SITE_TIMEZONE"`
+	Author      Author `json:"author" yaml:"author" toml:"author"`
+	Social      []Link `json:"social" yaml:"social" toml:"social"`
+}
+
+// Location returns the location of Timezone, or the local timezone if it is
+// empty
+func (c *SiteConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// Author is the author of the site
+type Author struct {
+	Name  string `json:"name" yaml:"name" toml:"name" env:"MIXDOWN_SITE_AUTHOR_NAME"`
+	Email string `json:"email" yaml:"email" toml:"email" env:"MIXDOWN_SITE_AUTHOR_EMAIL"`
+	URL   string `json:"url" yaml:"url" toml:"url" env:"MIXDOWN_SITE_AUTHOR_URL"`
+}
+
+// Link is the named link, e.g. the account of a social service
+type Link struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	URL  string `json:"url" yaml:"url" toml:"url"`
+}
+
+// Site is the site-level metadata that is exposed to every template as .Site
+type Site struct {
+	SiteConfig
+	BaseURL string
+	Sitemap string
+	Params  map[string]interface{}
+	Data    map[string]interface{}
+}
+
+// create the site-level metadata of the configuration and the data files
+func (b *Builder) newSite(data map[string]interface{}) *Site {
+	params := b.Params
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	return &Site{
+		SiteConfig: b.Site,
+		BaseURL:    b.BaseURL,
+		Sitemap:    b.Sitemap,
+		Params:     params,
+		Data:       data,
+	}
+}
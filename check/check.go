@@ -0,0 +1,253 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package check
+
+import (
+	"html"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mah0x211/mixdown/rex"
+)
+
+// kinds of problems
+const (
+	BrokenLink    = "broken link"
+	MissingAnchor = "missing anchor"
+	OrphanedPage  = "orphaned page"
+)
+
+// Problem is the representation of a problem found in a page
+type Problem struct {
+	Kind   string
+	Page   string
+	Ref    string
+	Reason string
+}
+
+type page struct {
+	refs    []string
+	anchors map[string]bool
+}
+
+// Checker verifies the references of the pages in the output directory
+type Checker struct {
+	rootdir string
+	prefix  string
+	extname string
+	pages   map[string]*page
+}
+
+// New allocate a instance of Checker
+func New(rootdir, baseURL, extname string) (*Checker, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// use only the path of base URL as a prefix
+	prefix := path.Clean("/" + u.Path)
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	return &Checker{
+		rootdir: rootdir,
+		prefix:  prefix,
+		extname: "." + extname,
+		pages:   make(map[string]*page),
+	}, nil
+}
+
+// isPage returns true if pathname is a HTML file
+func (c *Checker) isPage(pathname string) bool {
+	switch filepath.Ext(pathname) {
+	case c.extname, ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// parse extracts the references and anchors in the page
+func parse(buf []byte) *page {
+	p := &page{
+		anchors: make(map[string]bool),
+	}
+
+	buf = rex.HTMLComment.ReplaceAll(buf, nil)
+	for _, match := range rex.HTMLAttr.FindAllSubmatch(buf, -1) {
+		val := match[2]
+		if match[3] != nil {
+			val = match[3]
+		} else if match[4] != nil {
+			val = match[4]
+		}
+		v := strings.TrimSpace(html.UnescapeString(string(val)))
+
+		switch strings.ToLower(string(match[1])) {
+		case "id", "name":
+			p.anchors[v] = true
+		case "srcset":
+			// <url> [<descriptor>], ...
+			for _, src := range strings.Split(v, ",") {
+				if fields := strings.Fields(src); len(fields) > 0 {
+					p.refs = append(p.refs, fields[0])
+				}
+			}
+		default:
+			p.refs = append(p.refs, v)
+		}
+	}
+
+	return p
+}
+
+// resolve returns the pathname of the file in the root directory that is
+// referred from the page of pathname
+func (c *Checker) resolve(pathname string, u *url.URL) (string, string) {
+	base := &url.URL{
+		Path: c.prefix + filepath.ToSlash(pathname),
+	}
+	upath := base.ResolveReference(u).Path
+	if upath+"/" == c.prefix {
+		upath = c.prefix
+	} else if !strings.HasPrefix(upath, c.prefix) {
+		return "", "outside of base URL " + c.prefix
+	}
+
+	// resolve directory index
+	rel := filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+upath[len(c.prefix):]), "/"))
+	if rel == "" || strings.HasSuffix(upath, "/") {
+		rel = filepath.Join(rel, "index"+c.extname)
+	} else if info, err := os.Stat(filepath.Join(c.rootdir, rel)); err == nil && info.IsDir() {
+		rel = filepath.Join(rel, "index"+c.extname)
+	}
+
+	if info, err := os.Stat(filepath.Join(c.rootdir, rel)); err != nil {
+		return "", "file not found"
+	} else if info.IsDir() {
+		return "", "directory index not found"
+	}
+	return rel, ""
+}
+
+// Run parses all pages in the root directory and returns the found problems
+func (c *Checker) Run() ([]*Problem, error) {
+	// parse pages
+	pathnames := make([]string, 0)
+	err := filepath.Walk(c.rootdir, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if info.IsDir() || !c.isPage(pathname) {
+			return nil
+		}
+
+		buf, err := ioutil.ReadFile(pathname)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.rootdir, pathname)
+		if err != nil {
+			return err
+		}
+		pathnames = append(pathnames, rel)
+		c.pages[rel] = parse(buf)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pathnames)
+
+	// verify references
+	problems := make([]*Problem, 0)
+	linked := make(map[string]bool)
+	for _, pathname := range pathnames {
+		for _, ref := range c.pages[pathname].refs {
+			u, err := url.Parse(ref)
+			if err != nil {
+				problems = append(problems, &Problem{
+					Kind:   BrokenLink,
+					Page:   pathname,
+					Ref:    ref,
+					Reason: err.Error(),
+				})
+				continue
+			} else if u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+				// ignore external references
+				continue
+			}
+
+			target := pathname
+			if u.Path != "" {
+				var reason string
+				if target, reason = c.resolve(pathname, u); reason != "" {
+					problems = append(problems, &Problem{
+						Kind:   BrokenLink,
+						Page:   pathname,
+						Ref:    ref,
+						Reason: reason,
+					})
+					continue
+				} else if target != pathname {
+					linked[target] = true
+				}
+			}
+
+			// verify anchor
+			if p, ok := c.pages[target]; ok && u.Fragment != "" &&
+				u.Fragment != "top" && !p.anchors[u.Fragment] {
+				problems = append(problems, &Problem{
+					Kind:   MissingAnchor,
+					Page:   pathname,
+					Ref:    ref,
+					Reason: "anchor not found in " + filepath.ToSlash(target),
+				})
+			}
+		}
+	}
+
+	// pages that are not referred from other pages except the home and 404
+	for _, pathname := range pathnames {
+		switch pathname {
+		case "index" + c.extname, "404" + c.extname:
+			continue
+		}
+		if !linked[pathname] {
+			problems = append(problems, &Problem{
+				Kind:   OrphanedPage,
+				Page:   pathname,
+				Reason: "not referred from any other pages",
+			})
+		}
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,135 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// write the files of the pathnames and contents into the temporary directory
+func writeTestPages(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		pathname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		} else if err = ioutil.WriteFile(pathname, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParse(t *testing.T) {
+	p := parse([]byte(`<h1 id="top-title">x</h1><a name='old'></a>
+<!-- <a href="commented.html"></a> -->
+<a href="a.html?x=1&amp;y=2">a</a>
+<img SRC=b.png srcset="c.png 1x, d.png 2x,e.png">
+<link rel="stylesheet" href=" style.css ">`))
+
+	if want := []string{"a.html?x=1&y=2", "b.png", "c.png", "d.png", "e.png", "style.css"}; !reflect.DeepEqual(p.refs, want) {
+		t.Errorf("refs = %q, want %q", p.refs, want)
+	}
+	if want := map[string]bool{"top-title": true, "old": true}; !reflect.DeepEqual(p.anchors, want) {
+		t.Errorf("anchors = %v, want %v", p.anchors, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		baseURL string
+		files   map[string]string
+		want    []string
+	}{
+		{
+			name:    "no problems",
+			baseURL: "/",
+			files: map[string]string{
+				"index.html":      `<a href="2019/a.html#s1">a</a><a href="/tags/">t</a><a href="https://example.com/x.html">x</a><a href="mailto:a@example.com">m</a>`,
+				"2019/a.html":     `<h2 id="s1">s1</h2><a href="../index.html">home</a><a href="#s1">s1</a><a href="#top">top</a><img srcset="/img/a.png 1x">`,
+				"tags/index.html": `<a href="/">home</a>`,
+				"img/a.png":       "png",
+				"404.html":        `<a href="/">home</a>`,
+			},
+		},
+		{
+			name:    "broken links",
+			baseURL: "/",
+			files: map[string]string{
+				"index.html": `<a href="missing.html">x</a><img src="/img/missing.png" srcset="/img/a.png 1x, /img/b.png 2x"><a href="empty/">e</a><a href="%zz">z</a>`,
+				"img/a.png":  "png",
+				"empty/x.js": "js",
+			},
+			want: []string{
+				BrokenLink + "|index.html|missing.html|file not found",
+				BrokenLink + "|index.html|/img/missing.png|file not found",
+				BrokenLink + "|index.html|/img/b.png|file not found",
+				BrokenLink + "|index.html|empty/|file not found",
+				BrokenLink + "|index.html|%zz|" + `parse "%zz": invalid URL escape "%zz"`,
+			},
+		},
+		{
+			name:    "missing anchors",
+			baseURL: "/",
+			files: map[string]string{
+				"index.html": `<a id="here" href="#nowhere">x</a><a href="a.html#s2">a</a><a href="#here">h</a>`,
+				"a.html":     `<h2 id="s1">s1</h2><a href="index.html#here">h</a>`,
+			},
+			want: []string{
+				MissingAnchor + "|index.html|#nowhere|anchor not found in index.html",
+				MissingAnchor + "|index.html|a.html#s2|anchor not found in a.html",
+			},
+		},
+		{
+			name:    "orphaned pages",
+			baseURL: "/",
+			files: map[string]string{
+				"index.html":  `<a href="a.html">a</a>`,
+				"a.html":      `<a href="a.html">self</a>`,
+				"b.html":      `<a href="b.html#x">self</a><a href="a.html">a</a>`,
+				"404.html":    ``,
+				"c/index.htm": ``,
+			},
+			want: []string{
+				MissingAnchor + "|b.html|b.html#x|anchor not found in b.html",
+				OrphanedPage + "|b.html||not referred from any other pages",
+				OrphanedPage + "|c/index.htm||not referred from any other pages",
+			},
+		},
+		{
+			name:    "prefix of base URL",
+			baseURL: "https://example.com/blog",
+			files: map[string]string{
+				"index.html":  `<a href="/blog/2019/a.html">a</a><a href="/blog">home</a><a href="/about.html">about</a><a href="../about.html">about</a>`,
+				"2019/a.html": `<a href="../">home</a><a href="/blog/">home</a><a href="/blog/2019/b.html">b</a>`,
+			},
+			want: []string{
+				BrokenLink + "|2019/a.html|/blog/2019/b.html|file not found",
+				BrokenLink + "|index.html|/about.html|outside of base URL /blog/",
+				BrokenLink + "|index.html|../about.html|outside of base URL /blog/",
+			},
+		},
+	} {
+		dir := writeTestPages(t, c.files)
+		checker, err := New(dir, c.baseURL, "html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		problems, err := checker.Run()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		var got []string
+		for _, p := range problems {
+			got = append(got, strings.Join([]string{p.Kind, filepath.ToSlash(p.Page), p.Ref, p.Reason}, "|"))
+		}
+		if strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("%s: Run() =\n%s\nwant\n%s", c.name, strings.Join(got, "\n"), strings.Join(c.want, "\n"))
+		}
+	}
+}
@@ -27,8 +27,9 @@ package file
 import (
 	"bytes"
 	"fmt"
+	"html"
+	"html/template"
 	"io/ioutil"
-	"log"
 	"net/url"
 	"path/filepath"
 	"sort"
@@ -36,6 +37,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mah0x211/mixdown/logger"
 	"github.com/mah0x211/mixdown/rex"
 	"github.com/mah0x211/mixdown/util"
 
@@ -124,6 +126,11 @@ func (e *mdExtractor) Extract(node *blackfriday.Node, entering bool) (skip bool)
 	return
 }
 
+// TrackedFile is the file tracked by git.
+//
+// Summary and Content are html/template.HTML, not string, so that the html
+// templates output them without escaping. the callers that assign a string to
+// them must convert it with template.HTML after escaping the untrusted text.
 type TrackedFile struct {
 	isMarkdown bool
 	Href       string
@@ -135,9 +142,9 @@ type TrackedFile struct {
 	Ctime      string
 	Mtime      string
 	Subject    string
-	Summary    string
+	Summary    template.HTML
 	Hashtags   []string
-	Content    string
+	Content    template.HTML
 	Newer      *TrackedFile
 	Older      *TrackedFile
 }
@@ -146,13 +153,100 @@ func epoch2iso8601(epoch string) (string, error) {
 	if i64, err := strconv.ParseInt(epoch, 10, 64); err != nil {
 		return "", err
 	} else {
+		// the trailing "Z" is the designator of UTC
 		const fmtISO8601 = "20060102T150405Z"
-		return time.Unix(i64, 0).Format(fmtISO8601), nil
+		return time.Unix(i64, 0).UTC().Format(fmtISO8601), nil
+	}
+}
+
+// returns the year of epoch in the local time. it is used for the directory
+// of the article so that the published permalinks do not depend on the
+// timezone of Cdate.
+func epoch2year(epoch string) (string, error) {
+	if i64, err := strconv.ParseInt(epoch, 10, 64); err != nil {
+		return "", err
+	} else {
+		return time.Unix(i64, 0).Format("2006"), nil
 	}
 }
 
-// GetTrackedFiles ...
-func GetTrackedFiles(baseURL string, useEpochname bool, extname string) ([]*TrackedFile, []*TrackedFile, error) {
+func newTrackedFile(src, baseURL string, useEpochname bool, extname string) (*TrackedFile, string, error) {
+	// get last commit-log with following command;
+	// 	git log -n 1 --format=%ae/%cd/%s/%b -- ${file}
+	// 	  %ae: author email
+	//    %ct: committer date, UNIX timestamp
+	//    %s : subject
+	//    %b : body
+	// 	for more details: https://git-scm.com/docs/git-log
+	out, err := util.ExecCommand(
+		"git", "log", "--follow", "--format=%ae%x00%ct%x00%s%x00%b%x00",
+		"--", src,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to util.ExecCommand(): %s", err)
+	}
+
+	// extract segments
+	logs := strings.Split(string(out), "\000\n")
+	info := strings.Split(logs[0], "\000")
+	summary := strings.TrimSpace(info[3])
+	f := &TrackedFile{
+		isMarkdown: strings.HasSuffix(src, ".md"),
+		Source:     src,
+		Href:       src,
+		Pathname:   src,
+		Name:       util.Basename(src),
+		Author:     strings.SplitN(info[0], "@", 2)[0], // without domain name
+		Ctime:      info[1],
+		Mtime:      info[1],
+		Subject:    strings.TrimSpace(info[2]),
+		Summary:    template.HTML(html.EscapeString(summary)),
+	}
+
+	// set first-commit time to ctime
+	if len(logs) > 1 {
+		info = strings.SplitN(logs[len(logs)-1], "\000", 3)
+		f.Ctime = info[1]
+	}
+
+	// convert ctime to cdate
+	if f.Cdate, err = epoch2iso8601(f.Ctime); err != nil {
+		return nil, "", fmt.Errorf("failed to epoch2iso8601(): %s", err)
+	}
+
+	// preprocess
+	if f.isMarkdown {
+		// extract hashtags
+		f.Hashtags = rex.Hashtag.FindAllString(summary, -1)
+
+		// create pathname
+		year, err := epoch2year(f.Ctime)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to epoch2year(): %s", err)
+		}
+		if useEpochname {
+			f.Pathname = filepath.Join(year, f.Ctime+"."+extname)
+			f.Href = filepath.Join(baseURL, f.Pathname)
+		} else if src == "README.md" {
+			f.Pathname = f.Name + "." + extname
+			f.Href = filepath.Join(baseURL, f.Pathname)
+		} else {
+			f.Pathname = filepath.Join(year, f.Name+"."+extname)
+			f.Href = filepath.Join(
+				baseURL, year, url.PathEscape(f.Name)+"."+extname,
+			)
+		}
+
+		if err = f.Load(); err != nil {
+			return nil, "", fmt.Errorf("error File.Load(): %s", err)
+		}
+	}
+
+	return f, logs[0], nil
+}
+
+// GetTrackedFiles loads the tracked files of git on up to njob goroutines
+func GetTrackedFiles(baseURL string, useEpochname bool, extname string, njob int) ([]*TrackedFile, []*TrackedFile, error) {
 	// read tracked files of git
 	out, err := util.ExecCommand("git", "ls-files", "-z")
 	if err != nil {
@@ -160,87 +254,45 @@ func GetTrackedFiles(baseURL string, useEpochname bool, extname string) ([]*Trac
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(out)), "\000")
-	docs := make([]*TrackedFile, 0)
-	rsrc := make([]*TrackedFile, 0)
+	srcs := make([]string, 0, len(lines))
 	for _, src := range lines {
 		// skip EOF, LICENSE\..* and dotfiles
 		if src == "" || src == "LICENSE" || strings.HasPrefix(src, "LICENSE.") ||
 			strings.HasPrefix(src, ".") {
 			continue
 		}
+		srcs = append(srcs, src)
+	}
 
-		// get last commit-log with following command;
-		// 	git log -n 1 --format=%ae/%cd/%s/%b -- ${file}
-		// 	  %ae: author email
-		//    %ct: committer date, UNIX timestamp
-		//    %s : subject
-		//    %b : body
-		// 	for more details: https://git-scm.com/docs/git-log
-		out, err = util.ExecCommand(
-			"git", "log", "--follow", "--format=%ae%x00%ct%x00%s%x00%b%x00",
-			"--", src,
+	docs := make([]*TrackedFile, 0)
+	rsrc := make([]*TrackedFile, 0)
+	files := make([]*TrackedFile, len(srcs))
+	commits := make([]string, len(srcs))
+	elapsed := make([]time.Duration, len(srcs))
+	err = util.Parallel(njob, len(srcs), func(i int) (err error) {
+		start := time.Now()
+		files[i], commits[i], err = newTrackedFile(
+			srcs[i], baseURL, useEpochname, extname,
 		)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to util.ExecCommand(): %s", err)
-		}
-
-		// extract segments
-		logs := strings.Split(string(out), "\000\n")
-		log.Printf("%q - %q", src, logs[0])
-		info := strings.Split(logs[0], "\000")
-		f := &TrackedFile{
-			isMarkdown: strings.HasSuffix(src, ".md"),
-			Source:     src,
-			Href:       src,
-			Pathname:   src,
-			Name:       util.Basename(src),
-			Author:     strings.SplitN(info[0], "@", 2)[0], // without domain name
-			Ctime:      info[1],
-			Mtime:      info[1],
-			Subject:    strings.TrimSpace(info[2]),
-			Summary:    strings.TrimSpace(info[3]),
-		}
-
-		// set first-commit time to ctime
-		if len(logs) > 1 {
-			info = strings.SplitN(logs[len(logs)-1], "\000", 3)
-			f.Ctime = info[1]
-		}
-
-		// convert ctime to cdate
-		if f.Cdate, err = epoch2iso8601(f.Ctime); err != nil {
-			return nil, nil, fmt.Errorf("failed to epoch2iso8601(): %s", err)
-		}
-
-		// preprocess
-		if f.isMarkdown {
-			// extract hashtags
-			for _, match := range rex.Hashtag.FindAllStringIndex(f.Summary, -1) {
-				f.Hashtags = append(f.Hashtags, f.Summary[match[0]:match[1]])
-			}
-
-			// create pathname
-			if useEpochname {
-				f.Pathname = filepath.Join(f.Cdate[:4], f.Ctime+"."+extname)
-				f.Href = filepath.Join(baseURL, f.Pathname)
-			} else if src == "README.md" {
-				f.Pathname = f.Name + "." + extname
-				f.Href = filepath.Join(baseURL, f.Pathname)
-			} else {
-				f.Pathname = filepath.Join(f.Cdate[:4], f.Name+"."+extname)
-				f.Href = filepath.Join(
-					baseURL, f.Cdate[:4], url.PathEscape(f.Name)+"."+extname,
-				)
-			}
-
-			if err = f.Load(); err != nil {
-				return nil, nil, fmt.Errorf("error File.Load(): %s", err)
-			}
-
-			docs = append(docs, f)
+		elapsed[i] = time.Since(start)
+		return err
+	}, func(i int) error {
+		logger.Debugf("%q - %q", srcs[i], commits[i])
+		logger.File(logger.Event{
+			Source:   srcs[i],
+			Output:   files[i].Pathname,
+			Target:   "load",
+			Duration: elapsed[i],
+		})
+		if files[i].isMarkdown {
+			docs = append(docs, files[i])
 		} else {
-			rsrc = append(rsrc, f)
+			rsrc = append(rsrc, files[i])
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// sort by date in descending order
@@ -288,9 +340,9 @@ func (f *TrackedFile) Load() error {
 			f.Subject = extractor.Subject
 		}
 		if extractor.Summary != "" {
-			f.Summary = extractor.Summary
+			f.Summary = template.HTML(html.EscapeString(extractor.Summary))
 		}
-		f.Content = buf.String()
+		f.Content = template.HTML(buf.String())
 	}
 
 	return nil
@@ -1 +1,82 @@
 package file
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEpoch2ISO8601(t *testing.T) {
+	// Cdate is in UTC regardless of the local timezone
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = loc
+
+	if got, err := epoch2iso8601("1547003045"); err != nil {
+		t.Fatal(err)
+	} else if want := "20190109T030405Z"; got != want {
+		t.Errorf("epoch2iso8601() = %q, want %q", got, want)
+	}
+
+	if _, err = epoch2iso8601("yesterday"); err == nil {
+		t.Error("epoch2iso8601() with invalid epoch returns no error")
+	}
+}
+
+func TestNewTrackedFilePathYear(t *testing.T) {
+	// the article committed on New Year's Eve in UTC is in the directory of
+	// the year of the local time, as it was before Cdate became UTC
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = loc
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	} else if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err = ioutil.WriteFile("post.md", []byte("# hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"git", "init", "-q"},
+		{"git", "add", "post.md"},
+		{"git", "-c", "user.name=alice", "-c", "user.email=alice@example.com", "commit", "-q", "-m", "hello"},
+	} {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2018-12-31T20:00:00Z", "GIT_AUTHOR_DATE=2018-12-31T20:00:00Z")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s %s", args, err, out)
+		}
+	}
+
+	for _, c := range []struct {
+		useEpochname bool
+		pathname     string
+		href         string
+	}{
+		{false, filepath.Join("2019", "post.html"), "/blog/2019/post.html"},
+		{true, filepath.Join("2019", "1546286400.html"), "/blog/2019/1546286400.html"},
+	} {
+		f, _, err := newTrackedFile("post.md", "/blog/", c.useEpochname, "html")
+		if err != nil {
+			t.Fatal(err)
+		} else if f.Cdate != "20181231T200000Z" {
+			t.Errorf("Cdate = %q", f.Cdate)
+		} else if f.Pathname != c.pathname || f.Href != c.href {
+			t.Errorf("Pathname = %q, Href = %q, want %q, %q", f.Pathname, f.Href, c.pathname, c.href)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 module github.com/mah0x211/mixdown
 
-go 1.12
+go 1.16
 
 replace gopkg.in/russross/blackfriday.v2 v2.0.1 => github.com/russross/blackfriday/v2 v2.0.1
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
 	gopkg.in/russross/blackfriday.v2 v2.0.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,87 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/theme"
+	"github.com/mah0x211/mixdown/util"
+)
+
+// initialize creates the config file with the current options and the files
+// of the default theme. it refuses to initialize the site if the config file
+// exists or the theme directory is not empty.
+func (m *Mixdown) initialize(cfgFile string) error {
+	if _, err := os.Lstat(cfgFile); err == nil {
+		return fmt.Errorf("config file %q already exists", cfgFile)
+	} else if !os.IsNotExist(err) {
+		return err
+	} else if f, err := os.Open(m.ThemeDir); err == nil {
+		_, err = f.Readdirnames(1)
+		f.Close()
+		if err == nil {
+			return fmt.Errorf("theme directory %q is not empty", m.ThemeDir)
+		} else if err != io.EOF {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// create config file
+	logger.Infof("create config file %q", cfgFile)
+	if buf, err := m.Config.Marshal(cfgFile); err != nil {
+		return fmt.Errorf("failed to Config.Marshal(): %s", err)
+	} else if f, err := util.CreateFile(cfgFile); err != nil {
+		return fmt.Errorf("failed to util.CreateFile(): %s", err)
+	} else if _, err = f.Write(buf); err != nil {
+		f.Close()
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+
+	// create theme files
+	logger.Infof("create theme files %q", m.ThemeDir)
+	if err := theme.ExportDefault(m.ThemeDir); err != nil {
+		return fmt.Errorf("failed to theme.ExportDefault(): %s", err)
+	}
+
+	return nil
+}
+
+// eject writes the files of the default theme embedded in the binary into the
+// theme directory to customize them. the existing files are not overwritten.
+func (m *Mixdown) eject() error {
+	logger.Infof("eject the default theme into %q", m.ThemeDir)
+	if err := theme.ExportDefault(m.ThemeDir); err != nil {
+		return fmt.Errorf("failed to theme.ExportDefault(): %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mah0x211/mixdown/builder"
+)
+
+func TestInitialize(t *testing.T) {
+	chdirTemp(t)
+	cfg := builder.NewConfig()
+	cfg.BaseURL = "/blog/"
+	m := &Mixdown{&builder.Builder{Config: *cfg}}
+	cfgFile := filepath.Join(builder.DotDir, "config.json")
+	if err := m.initialize(cfgFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the config file of the current options
+	var v map[string]interface{}
+	if buf, err := ioutil.ReadFile(cfgFile); err != nil {
+		t.Fatal(err)
+	} else if err = json.Unmarshal(buf, &v); err != nil {
+		t.Fatal(err)
+	} else if v["baseURL"] != "/blog/" || v["jobs"] != 0.0 {
+		t.Errorf("config file = %s", buf)
+	}
+
+	// the files of the default theme
+	for _, name := range []string{"layout.html", "home.mix.html@layout.html", "article.mix.html@layout.html", "feed.mix.xml"} {
+		if _, err := os.Stat(filepath.Join(m.ThemeDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// the site that is already initialized
+	if err := m.initialize(cfgFile); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("initialize() of the existing config file returns %v", err)
+	}
+	if err := m.initialize(filepath.Join(builder.DotDir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "is not empty") {
+		t.Errorf("initialize() of the non-empty theme directory returns %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(builder.DotDir, "config.yaml")); err == nil {
+		t.Error("config file is created by the refused initialize()")
+	}
+
+	// the empty theme directory is initialized
+	chdirTemp(t)
+	if err := os.MkdirAll(m.ThemeDir, 0755); err != nil {
+		t.Fatal(err)
+	} else if err = m.initialize(cfgFile); err != nil {
+		t.Errorf("initialize() of the empty theme directory returns %s", err)
+	}
+}
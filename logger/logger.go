@@ -0,0 +1,214 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of log messages
+type Level int
+
+const (
+	ERROR Level = iota
+	WARN
+	INFO
+	DEBUG
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	if l < ERROR || l > DEBUG {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level of name
+func ParseLevel(name string) (Level, error) {
+	for i, v := range levelNames {
+		if strings.EqualFold(name, v) {
+			return Level(i), nil
+		}
+	}
+	return INFO, fmt.Errorf("unknown log level %q - level must be one of %s", name, strings.Join(levelNames, ", "))
+}
+
+// Format is the output format of log messages
+type Format int
+
+const (
+	TEXT Format = iota
+	JSON
+)
+
+var formatNames = []string{"text", "json"}
+
+func (f Format) String() string {
+	if f < TEXT || f > JSON {
+		return fmt.Sprintf("format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// ParseFormat returns the format of name
+func ParseFormat(name string) (Format, error) {
+	for i, v := range formatNames {
+		if strings.EqualFold(name, v) {
+			return Format(i), nil
+		}
+	}
+	return TEXT, fmt.Errorf("unknown log format %q - format must be one of %s", name, strings.Join(formatNames, ", "))
+}
+
+// Event is the representation of a file processed by a build target
+type Event struct {
+	Source   string
+	Output   string
+	Target   string
+	Duration time.Duration
+}
+
+type record struct {
+	Time     string   `json:"time"`
+	Level    string   `json:"level"`
+	Msg      string   `json:"msg"`
+	Source   string   `json:"source,omitempty"`
+	Output   string   `json:"output,omitempty"`
+	Target   string   `json:"target,omitempty"`
+	Duration *float64 `json:"duration_ms,omitempty"`
+}
+
+var (
+	mu     sync.Mutex
+	out    io.Writer = os.Stderr
+	level            = INFO
+	format           = TEXT
+)
+
+// SetOutput sets the output destination
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// SetLevel sets the maximum level of messages to output
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// SetFormat sets the output format
+func SetFormat(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+}
+
+// Enabled returns true if the messages of l are output
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l <= level
+}
+
+func output(l Level, msg string, ev *Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l > level {
+		return
+	}
+
+	now := time.Now()
+	if format == JSON {
+		rec := record{
+			Time:  now.Format(time.RFC3339Nano),
+			Level: l.String(),
+			Msg:   msg,
+		}
+		if ev != nil {
+			ms := float64(ev.Duration) / float64(time.Millisecond)
+			rec.Source, rec.Output, rec.Target = ev.Source, ev.Output, ev.Target
+			rec.Duration = &ms
+		}
+		if buf, err := json.Marshal(rec); err == nil {
+			out.Write(append(buf, '\n'))
+		}
+		return
+	}
+
+	if ev != nil {
+		switch {
+		case ev.Source != "" && ev.Output != "":
+			msg = fmt.Sprintf("%s %q -> %q", msg, ev.Source, ev.Output)
+		case ev.Source != "":
+			msg = fmt.Sprintf("%s %q", msg, ev.Source)
+		default:
+			msg = fmt.Sprintf("%s -> %q", msg, ev.Output)
+		}
+		msg = fmt.Sprintf("%s (%s)", msg, ev.Duration.Round(time.Microsecond))
+	}
+	fmt.Fprintf(out, "%s %-7s %s\n", now.Format("2006/01/02 15:04:05"), "["+l.String()+"]", msg)
+}
+
+// Errorf outputs the message at the error level
+func Errorf(f string, v ...interface{}) {
+	output(ERROR, fmt.Sprintf(f, v...), nil)
+}
+
+// Warnf outputs the message at the warn level
+func Warnf(f string, v ...interface{}) {
+	output(WARN, fmt.Sprintf(f, v...), nil)
+}
+
+// Infof outputs the message at the info level
+func Infof(f string, v ...interface{}) {
+	output(INFO, fmt.Sprintf(f, v...), nil)
+}
+
+// Debugf outputs the message at the debug level
+func Debugf(f string, v ...interface{}) {
+	output(DEBUG, fmt.Sprintf(f, v...), nil)
+}
+
+// Fatalf outputs the message at the error level and exits with status 1
+func Fatalf(f string, v ...interface{}) {
+	output(ERROR, fmt.Sprintf(f, v...), nil)
+	os.Exit(1)
+}
+
+// File outputs the event of a file processed by the target at the info level
+func File(ev Event) {
+	output(INFO, ev.Target, &ev)
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirect the output into the buffer with l and f, and restore the defaults
+// after the test
+func setTestOutput(t *testing.T, l Level, f Format) *bytes.Buffer {
+	t.Helper()
+	b := &bytes.Buffer{}
+	SetOutput(b)
+	SetLevel(l)
+	SetFormat(f)
+	t.Cleanup(func() {
+		SetOutput(os.Stderr)
+		SetLevel(INFO)
+		SetFormat(TEXT)
+	})
+	return b
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	for name, want := range map[string]Level{"error": ERROR, "WARN": WARN, "Info": INFO, "debug": DEBUG} {
+		if l, err := ParseLevel(name); err != nil || l != want {
+			t.Errorf("ParseLevel(%q) = %v, %v", name, l, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel() with unknown level returns no error")
+	}
+
+	for name, want := range map[string]Format{"text": TEXT, "JSON": JSON} {
+		if f, err := ParseFormat(name); err != nil || f != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", name, f, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat() with unknown format returns no error")
+	}
+
+	if s := Level(9).String(); s != "level(9)" {
+		t.Errorf("Level(9) = %q", s)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for _, c := range []struct {
+		level Level
+		want  []string
+	}{
+		{ERROR, []string{"[error] e"}},
+		{WARN, []string{"[error] e", "[warn]  w"}},
+		{INFO, []string{"[error] e", "[warn]  w", "[info]  i", "[info]  article \"a.md\" -> \"a.html\" (1.5ms)"}},
+		{DEBUG, []string{"[error] e", "[warn]  w", "[info]  i", "[info]  article \"a.md\" -> \"a.html\" (1.5ms)", "[debug] d"}},
+	} {
+		b := setTestOutput(t, c.level, TEXT)
+		Errorf("%s", "e")
+		Warnf("%s", "w")
+		Infof("%s", "i")
+		File(Event{Source: "a.md", Output: "a.html", Target: "article", Duration: 1500 * time.Microsecond})
+		Debugf("%s", "d")
+
+		var got []string
+		re := regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} (.+)$`)
+		for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+			if m := re.FindStringSubmatch(line); m == nil {
+				t.Errorf("invalid line %q", line)
+			} else {
+				got = append(got, m[1])
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("level %s =\n%s\nwant\n%s", c.level, strings.Join(got, "\n"), strings.Join(c.want, "\n"))
+		}
+		if !Enabled(c.level) || Enabled(c.level+1) {
+			t.Errorf("Enabled() of level %s", c.level)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b := setTestOutput(t, INFO, JSON)
+	Warnf("hello %q", "world")
+	File(Event{Source: "a.md", Output: "a.html", Target: "article", Duration: 1500 * time.Microsecond})
+	Debugf("ignored")
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), b.String())
+	}
+	for i, want := range []map[string]interface{}{
+		{"level": "warn", "msg": `hello "world"`},
+		{"level": "info", "msg": "article", "source": "a.md", "output": "a.html", "target": "article", "duration_ms": 1.5},
+	} {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &rec); err != nil {
+			t.Fatalf("line %q: %s", lines[i], err)
+		}
+		if s, ok := rec["time"].(string); !ok {
+			t.Errorf("line %q has no time", lines[i])
+		} else if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			t.Errorf("invalid time %q: %s", s, err)
+		}
+		delete(rec, "time")
+		if len(rec) != len(want) {
+			t.Errorf("line %q, want the fields %v", lines[i], want)
+		}
+		for k, v := range want {
+			if rec[k] != v {
+				t.Errorf("%s of line %q = %v, want %v", k, lines[i], rec[k], v)
+			}
+		}
+	}
+}
@@ -20,509 +20,292 @@
 // IN THE SOFTWARE.
 //
 // Created by Masatoshi Fukunaga on 19/01/09
-//
 
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/url"
 	"os"
 	"path/filepath"
-	"sort"
-	"strconv"
 	"strings"
 
-	"github.com/mah0x211/mixdown/file"
-	"github.com/mah0x211/mixdown/rex"
-	"github.com/mah0x211/mixdown/theme"
-	"github.com/mah0x211/mixdown/util"
+	"github.com/mah0x211/mixdown/builder"
+	"github.com/mah0x211/mixdown/logger"
 )
 
+// Mixdown is the command-line interface of the builder
 type Mixdown struct {
-	// configuration parameters
-	BaseURL      string `json:"baseURL,omitempty"`
-	OutDir       string `json:"outdir,omitempty"`
-	UseEpochname bool   `json:"useEpochname,omitempty"`
-	Extname      string `json:"extname,omitempty"`
-	NArchive     int    `json:"narchive,omitempty"`
-	Sitemap      string `json:"sitemap,omitempty"`
-
-	SitemapFile *os.File            `json:"-"`
-	ThemeDir    string              `json:"-"`
-	Theme       *theme.Theme        `json:"-"`
-	Hashtags    []string            `json:"-"`
-	Documents   []*file.TrackedFile `json:"-"`
-	Resources   []*file.TrackedFile `json:"-"`
-	Readme      *file.TrackedFile   `json:"-"`
-}
-
-const MixdownDotDir string = ".mixdown/"
-
-const (
-	pageTypeHome = iota + 1
-	pageTypeArticle
-	pageTypeArchive
-	pageTypeTag
-)
-
-func createMixdown() *Mixdown {
-	return &Mixdown{
-		BaseURL:      "/",
-		OutDir:       "docs",
-		UseEpochname: false,
-		Extname:      "html",
-		NArchive:     40,
-
-		ThemeDir: filepath.Join(MixdownDotDir, "theme"),
-	}
+	*builder.Builder
 }
 
-// render sitemap
-func (m *Mixdown) renderSitemap(pathname string) error {
-	if m.SitemapFile != nil {
-		// remove outdir prefix
-		pathname = filepath.Join(m.BaseURL, strings.TrimPrefix(pathname, m.OutDir))
-		// escape-uri-component
-		segs := make([]string, 1)
-		for _, seg := range strings.Split(pathname, "/") {
-			segs = append(segs, url.PathEscape(seg))
-		}
-		uri := m.Sitemap + "/" + filepath.Join(segs...) + "\n"
-		if _, err := m.SitemapFile.WriteString(uri); err != nil {
-			return err
+// create the mixdown of the verified copy of cfg
+func newMixdown(cfg *builder.Config) (*Mixdown, error) {
+	b, err := builder.New(cfg)
+	if errs, ok := err.(builder.ConfigErrors); ok {
+		for _, e := range errs {
+			logger.Errorf("%s", e)
 		}
+		return nil, fmt.Errorf("found %d problems in the configuration", len(errs))
+	} else if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return &Mixdown{b}, nil
 }
 
-// render tags
-func (m *Mixdown) renderTags() error {
-	type stTag struct {
-		BaseURL  string
-		PageType int
-		Page     int
-		NPage    *int
-		Readme   *file.TrackedFile
-		Hashtags []string
-		Href     string
-		Pathname string
-		Subject  string
-		Docs     []*file.TrackedFile
-		Newer    *stTag
-		Older    *stTag
+// build the site into outdir, and print the plan to stdout in dry-run mode
+func (m *Mixdown) build() error {
+	report, err := m.Build(context.Background())
+	if err != nil {
+		return err
 	}
+	return report.WritePlan(os.Stdout)
+}
 
-	// grouping files with hashtags
-	tags := make(map[string]*stTag)
-	ndoc := m.NArchive
-	tagExists := make(map[string]bool)
-	for _, doc := range m.Documents {
-		// maintain references for readme.html
-		if strings.HasPrefix(doc.Source, "README.") {
-			m.Readme = doc
-		}
-
-		// grouping with hashtags
-		for _, hashtag := range doc.Hashtags {
-			tagName := hashtag[1:]
-			href := filepath.Join(m.BaseURL, "t", url.PathEscape(tagName)) + "/"
-			doc.Summary = strings.Replace(
-				doc.Summary, hashtag,
-				fmt.Sprintf("<a href=%q>%s</a>", href, hashtag), 1,
-			)
-
-			// insert hashtag into list
-			if _, ok := tagExists[hashtag]; !ok {
-				tagExists[hashtag] = true
-				m.Hashtags = append(m.Hashtags, tagName)
-			}
-
-			if tag, ok := tags[hashtag]; ok {
-				// create next page
-				if len(tag.Docs) == ndoc {
-					*tag.NPage++
-					pageName := strconv.Itoa(*tag.NPage) + "." + m.Extname
-					tag.Older = &stTag{
-						BaseURL:  m.BaseURL,
-						PageType: pageTypeTag,
-						Page:     *tag.NPage,
-						NPage:    tag.NPage,
-						Href:     filepath.Join(href, pageName),
-						Pathname: filepath.Join("t", tagName, pageName),
-						Subject:  hashtag,
-						Newer:    tag,
-					}
-					tag = tag.Older
-				}
-				tag.Docs = append(tag.Docs, doc)
+// load config files into cfg in order, the environment variables and then
+// the command-line parameters of fs that were set explicitly, so that the
+// latter take precedence. fields are the field names of the configuration
+// parameters by flag name.
+func loadConfig(cfg *builder.Config, fs *flag.FlagSet, fields map[string]string, cfgFiles []string) error {
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
 
-			} else {
-				page := 1
-				tags[hashtag] = &stTag{
-					BaseURL:  m.BaseURL,
-					PageType: pageTypeTag,
-					Page:     page,
-					NPage:    &page,
-					Href:     filepath.Join(href, "index."+m.Extname),
-					Pathname: filepath.Join("t", tagName, "index."+m.Extname),
-					Subject:  hashtag,
-					Docs:     []*file.TrackedFile{doc},
-				}
-			}
+	for _, pathname := range cfgFiles {
+		if err := cfg.Load(pathname); err != nil {
+			return err
 		}
 	}
-
-	// sort hashtags by name
-	sort.Slice(m.Hashtags, func(i, j int) bool {
-		return m.Hashtags[i] < m.Hashtags[j]
-	})
-
-	// render tags
-	for tagName, tag := range tags {
-		for tag != nil {
-			tag.Readme = m.Readme
-			tag.Hashtags = m.Hashtags
-			pathname := filepath.Join(m.OutDir, tag.Pathname)
-			log.Printf("%q -> %q", tagName, pathname)
-
-			// render
-			if ofile, err := util.CreateFile(pathname); err != nil {
-				return fmt.Errorf("error util.CreateFile(): %s", err)
-			} else if err = m.Theme.Execute(ofile, "tag", tag); err != nil {
-				return fmt.Errorf("error Template.Execute(): %s", err)
-			} else {
-				ofile.Close()
-				if err = m.renderSitemap(pathname); err != nil {
-					return err
-				}
-			}
-			tag = tag.Older
+	if err := cfg.LoadEnv(os.LookupEnv); err != nil {
+		return err
+	}
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		} else if field, ok := fields[name]; ok {
+			cfg.SetSource(field, "flag -"+name)
 		}
 	}
 
 	return nil
 }
 
-// render articles
-func (m *Mixdown) renderArticles() error {
-	type stArticle struct {
-		*file.TrackedFile
-		BaseURL  string
-		PageType int
-		Readme   *file.TrackedFile
-		Hashtags []string
+// bind the command-line parameters to the configuration parameters, and
+// returns the field names of them by flag name. the parameters of the build
+// command are bound if build is true.
+func bindFlags(fs *flag.FlagSet, cfg *builder.Config, build bool) map[string]string {
+	fields := make(map[string]string)
+	bind := func(name, field string) string {
+		fields[name] = field
+		return name
 	}
 
-	for _, doc := range m.Documents {
-		article := stArticle{
-			doc, m.BaseURL, pageTypeArticle, m.Readme, m.Hashtags,
-		}
-		pathname := filepath.Join(m.OutDir, doc.Pathname)
-		log.Printf("%q -> %q", doc.Source, pathname)
-
-		// render
-		if ofile, err := util.CreateFile(pathname); err != nil {
-			return fmt.Errorf("error util.CreateFile(): %s", err)
-		} else if err = m.Theme.Execute(ofile, "article", article); err != nil {
-			return fmt.Errorf("error Template.Execute(): %s", err)
-		} else {
-			ofile.Close()
-			if err = m.renderSitemap(pathname); err != nil {
-				return err
-			}
-		}
-		doc.Unload()
+	fs.StringVar(&cfg.BaseURL, bind("base-url", "BaseURL"), cfg.BaseURL, "base URL for all relative URLs in a document.")
+	fs.StringVar(&cfg.OutDir, bind("outdir", "OutDir"), cfg.OutDir, "pathname of output directory. if not specified, automatically generate a temporary name.")
+	fs.BoolVar(&cfg.UseEpochname, bind("use-epochname", "UseEpochname"), cfg.UseEpochname, "use epoch time of file creation time as filename. (default \"false\")")
+	fs.StringVar(&cfg.Extname, bind("extname", "Extname"), cfg.Extname, "extension name of the output file.")
+	fs.IntVar(&cfg.NArchive, bind("narchive", "NArchive"), cfg.NArchive, "number of articles in archive.")
+	fs.StringVar(&cfg.Sitemap, bind("sitemap", "Sitemap"), cfg.Sitemap, "hostname of fully qualified url.")
+	fs.IntVar(&cfg.Jobs, bind("jobs", "Jobs"), cfg.Jobs, "number of files to load and render in parallel. 0 means the number of CPUs.")
+	fs.StringVar(&cfg.Backup, bind("backup", "Backup"), cfg.Backup, "pathname of directory to keep the previous outdir. if not specified, it will be removed.")
+	if build {
+		fs.BoolVar(&cfg.DryRun, bind("dry-run", "DryRun"), cfg.DryRun, "print the outputs of build without writing them.")
+		fs.BoolVar(&cfg.Force, bind("force", "Force"), cfg.Force, "overwrite the non-empty outdir that was not created by mixdown.")
 	}
 
-	return nil
+	return fields
 }
 
-// render archives into archive/ directory
-func (m *Mixdown) renderArchives() error {
-	type stArchive struct {
-		BaseURL  string
-		PageType int
-		Page     int
-		NPage    *int
-		Readme   *file.TrackedFile
-		Hashtags []string
-		Href     string
-		Pathname string
-		Subject  string
-		Docs     []*file.TrackedFile
-		First    *file.TrackedFile
-		Last     *file.TrackedFile
-		Newer    *stArchive
-		Older    *stArchive
-	}
+func main() {
+	cfg := builder.NewConfig()
 
-	// collect files to archive
-	page := 1
-	arc := &stArchive{
-		PageType: pageTypeArchive,
-		BaseURL:  m.BaseURL,
-		Page:     page,
-		NPage:    &page,
-		Pathname: filepath.Join("archive", "index."+m.Extname),
+	// select subcommand
+	cmd, args := "build", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
 	}
-	arc.Href = filepath.Join(m.BaseURL, arc.Pathname)
-	head := arc
-	ndoc := m.NArchive
-	for _, doc := range m.Documents {
-		// create next page
-		if len(arc.Docs) == ndoc {
-			arc.First, arc.Last = arc.Docs[0], arc.Docs[ndoc-1]
-			page++
-			arc.Older = &stArchive{
-				BaseURL:  m.BaseURL,
-				PageType: pageTypeArchive,
-				Page:     page,
-				NPage:    &page,
-				Pathname: filepath.Join("archive", strconv.Itoa(page)+"."+m.Extname),
-				Newer:    arc,
-			}
-			arc = arc.Older
-			arc.Href = filepath.Join(m.BaseURL, arc.Pathname)
-		}
-		arc.Docs = append(arc.Docs, doc)
+	switch cmd {
+	case "build", "serve", "init", "new", "check", "eject":
+	default:
+		logger.Fatalf("unknown command %q", cmd)
 	}
 
-	if len(arc.Docs) > 0 {
-		arc.First, arc.Last = arc.Docs[0], arc.Docs[len(arc.Docs)-1]
-		// render archives
-		arc = head
-		for arc != nil {
-			arc.Readme = m.Readme
-			arc.Hashtags = m.Hashtags
-			pathname := filepath.Join(m.OutDir, arc.Pathname)
-			log.Printf("%q -> %q", arc.Pathname, pathname)
-
-			// render
-			if ofile, err := util.CreateFile(pathname); err != nil {
-				return fmt.Errorf("error util.CreateFile(): %s", err)
-			} else if err = m.Theme.Execute(ofile, "archive", arc); err != nil {
-				return fmt.Errorf("error Template.Execute(): %s", err)
-			} else {
-				ofile.Close()
-				if err = m.renderSitemap(pathname); err != nil {
-					return err
-				}
-			}
-			arc = arc.Older
-		}
+	// parse command-line parameters
+	fields := bindFlags(flag.CommandLine, cfg, cmd == "build")
+	cfgFile := os.Getenv("MIXDOWN_CONFIG")
+	flag.StringVar(&cfgFile, "config", cfgFile, "pathname of config file. if not specified, config.{json,yaml,yml,toml} in the \""+builder.DotDir+"\" directory is used in this order.")
+	env := os.Getenv("MIXDOWN_ENV")
+	flag.StringVar(&env, "env", env, "name of environment to load the overlay config file <config>.<env>.<ext> after the config file.")
+	quiet := false
+	flag.BoolVar(&quiet, "quiet", quiet, "output only warnings and errors.")
+	logLevel := logger.INFO.String()
+	flag.StringVar(&logLevel, "log-level", logLevel, "maximum level of log messages; error, warn, info or debug.")
+	logFormat := logger.TEXT.String()
+	flag.StringVar(&logFormat, "log-format", logFormat, "format of log messages; text or json.")
+	watch := false
+	flag.BoolVar(&watch, "watch", watch, "watch the changes of files and rebuild the site.")
+	postCheck := false
+	if cmd == "build" {
+		flag.BoolVar(&postCheck, "check", postCheck, "check the broken links of outputs after build.")
 	}
-
-	return nil
-}
-
-// render home
-func (m *Mixdown) renderHome() error {
-	type stHome struct {
-		BaseURL  string
-		PageType int
-		Readme   *file.TrackedFile
-		Hashtags []string
-		Subject  string
-		Docs     []*file.TrackedFile
+	addr := "localhost:8080"
+	if cmd == "serve" {
+		flag.StringVar(&addr, "addr", addr, "TCP address for the server to listen on.")
 	}
-
-	home := stHome{
-		m.BaseURL, pageTypeHome, m.Readme, m.Hashtags, "", m.Documents,
+	var post struct {
+		archetype string
+		dir       string
+		slug      string
+		add       bool
+	}
+	if cmd == "new" {
+		flag.StringVar(&post.archetype, "archetype", "default", "name of the archetype file in the \""+cfg.ArchetypeDir+"\" directory.")
+		flag.StringVar(&post.dir, "dir", ".", "pathname of the directory to create the file.")
+		flag.StringVar(&post.slug, "slug", "", "filename without extension. if not specified, generated from the subject.")
+		flag.BoolVar(&post.add, "add", false, "stage the created file with git add.")
 	}
-	pathname := filepath.Join(m.OutDir, "index."+m.Extname)
-	log.Printf("index -> %q", pathname)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [build|serve|init|check|eject] [options]\n       %s new [options] <subject> [#hashtag ...]\n", filepath.Base(os.Args[0]), filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
 
-	// render
-	if ofile, err := util.CreateFile(pathname); err != nil {
-		return fmt.Errorf("error util.CreateFile(): %s", err)
-	} else if err = m.Theme.Execute(ofile, "home", home); err != nil {
-		return fmt.Errorf("error Template.Execute(): %s", err)
+	// setup logger
+	if lv, err := logger.ParseLevel(logLevel); err != nil {
+		logger.Fatalf("error invalid log-level - %s", err)
+	} else if quiet && lv > logger.WARN {
+		logger.SetLevel(logger.WARN)
 	} else {
-		ofile.Close()
-		if err = m.renderSitemap(pathname); err != nil {
-			return err
-		}
+		logger.SetLevel(lv)
+	}
+	if f, err := logger.ParseFormat(logFormat); err != nil {
+		logger.Fatalf("error invalid log-format - %s", err)
+	} else {
+		logger.SetFormat(f)
 	}
 
-	return nil
-}
-
-// render resource
-func (m *Mixdown) renderResources() error {
-	for _, rsrc := range m.Resources {
-		pathname := filepath.Join(m.OutDir, rsrc.Pathname)
-		if err := util.CopyFile(rsrc.Pathname, pathname); err != nil {
-			return fmt.Errorf("error util.CopyFile(): %s", err)
+	// load mixdown config files
+	var cfgFiles []string
+	if cmd != "init" {
+		files, err := builder.FindConfigFiles(cfgFile, env)
+		if err != nil {
+			logger.Fatalf("failed to builder.FindConfigFiles(): %s", err)
+		} else if err = loadConfig(cfg, flag.CommandLine, fields, files); err != nil {
+			logger.Fatalf("%s", err)
 		}
-		rsrc.Unload()
+		cfgFiles = files
+	} else if cfgFile == "" {
+		cfgFile = filepath.Join(builder.DotDir, "config.json")
 	}
 
-	return nil
-}
-
-// render
-func (m *Mixdown) render(target string) error {
-	switch target {
-	case "tag":
-		return m.renderTags()
-	case "article":
-		return m.renderArticles()
-	case "archive":
-		return m.renderArchives()
-	case "home":
-		return m.renderHome()
-	case "resources":
-		return m.renderResources()
-	default:
-		return fmt.Errorf("unknown target %q", target)
+	// create markdown file
+	if cmd == "new" {
+		if m, err := newMixdown(cfg); err != nil {
+			logger.Fatalf("%s", err)
+		} else if err = m.newPost(post.archetype, post.dir, post.slug, post.add, flag.Args()); err != nil {
+			logger.Fatalf("failed to newPost(): %s", err)
+		}
+		logger.Infof("goodbye")
+		return
 	}
-}
 
-func main() {
-	m := createMixdown()
-
-	// load mixdown config file
-	cfgFile := filepath.Join(MixdownDotDir, "config.json")
-	if f, err := os.Open(cfgFile); err == nil {
-		log.Println(strings.Repeat("*", 80))
-		log.Printf("LOAD CONFIG FILE %q", cfgFile)
-		if err = json.NewDecoder(f).Decode(m); err != nil {
-			log.Fatalf("failed to load %q: %s", cfgFile, err)
+	// export the default theme files to customize them
+	if cmd == "eject" {
+		if m, err := newMixdown(cfg); err != nil {
+			logger.Fatalf("%s", err)
+		} else if err = m.eject(); err != nil {
+			logger.Fatalf("failed to eject(): %s", err)
 		}
-		f.Close()
+		logger.Infof("goodbye")
+		return
+	}
 
-		// verify options
-		if strings.HasPrefix(m.OutDir, MixdownDotDir) {
-			log.Fatalf("error invalid outdir configuration %q - cannot be output to the %q directory", m.OutDir, MixdownDotDir)
-		} else if !rex.Extname.MatchString(m.Extname) {
-			log.Fatalf("error invalid extname configuration %q - extname must be [0-9a-zA-Z_]+", m.Extname)
-		} else if m.NArchive < 1 {
-			log.Fatalf("error invalid narchive configuration %d - narchive must be greater than 0", m.NArchive)
+	// create config file and theme files
+	if cmd == "init" {
+		if m, err := newMixdown(cfg); err != nil {
+			logger.Fatalf("%s", err)
+		} else if err = m.initialize(cfgFile); err != nil {
+			logger.Fatalf("failed to initialize(): %s", err)
 		}
-		log.Println(strings.Repeat("*", 80))
-
-	} else if !os.IsNotExist(err) {
-		log.Fatalf("failed to load %q: %s", cfgFile, err)
+		logger.Infof("goodbye")
+		return
 	}
 
-	// parse command-line parameters
-	flag.StringVar(&m.BaseURL, "base-url", m.BaseURL, "base URL for all relative URLs in a document.")
-	flag.StringVar(&m.OutDir, "outdir", m.OutDir, "pathname of output directory. if not specified, automatically generate a temporary name.")
-	flag.BoolVar(&m.UseEpochname, "use-epochname", m.UseEpochname, "use epoch time of file creation time as filename. (default \"false\")")
-	flag.StringVar(&m.Extname, "extname", m.Extname, "extension name of the output file.")
-	flag.IntVar(&m.NArchive, "narchive", m.NArchive, "number of articles in archive.")
-	flag.StringVar(&m.Sitemap, "sitemap", m.Sitemap, "hostname of fully qualified url.")
-	flag.Parse()
+	// serve command always builds into a temporary directory
+	if cmd == "serve" {
+		if tmpdir, err := ioutil.TempDir("", "mixdown-"); err != nil {
+			logger.Fatalf("failed to ioutil.TempDir(): %s", err)
+		} else {
+			cfg.OutDir = tmpdir
+			cfg.Backup = ""
+			cfg.DryRun = false
+			cfg.SetSource("OutDir", "temporary")
+			cfg.SetSource("Backup", "temporary")
+		}
+	}
 
 	// verify outdir
-	if m.OutDir == "" {
+	if cfg.OutDir == "" && !cfg.DryRun {
 		// generate temporary name
 		if tmpdir, err := ioutil.TempDir("./", "mixdown-"); err != nil {
-			log.Fatalf("failed to ioutil.TempDir(): %s", err)
+			logger.Fatalf("failed to ioutil.TempDir(): %s", err)
 		} else {
-			m.OutDir = tmpdir
+			cfg.OutDir = tmpdir
+			cfg.SetSource("OutDir", "temporary")
 		}
 	}
 
 	// verify options
-	if strings.HasPrefix(m.OutDir, MixdownDotDir) {
-		log.Fatalf("error invalid outdir %q - cannot be output to the %q directory", m.OutDir, MixdownDotDir)
-	} else if !rex.Extname.MatchString(m.Extname) {
-		log.Fatalf("error invalid extname %q - extname must be [0-9a-zA-Z_]+", m.Extname)
-	} else if m.NArchive < 1 {
-		log.Fatalf("error invalid narchive %d - narchive must be greater than 0", m.NArchive)
-	}
-	m.OutDir = filepath.Join(m.OutDir)
-
-	m.Sitemap = strings.TrimSpace(m.Sitemap)
-	if m.Sitemap != "" {
-		if u, err := url.Parse(m.Sitemap); err != nil {
-			log.Fatalf("error invalid sitemap %q - %s", m.Sitemap, err)
-		} else if u.Scheme == "" || u.Host == "" {
-			log.Fatalf("error invalid sitemap %q - sitemap must be fully qualified url", m.Sitemap)
-		} else if u.Scheme != "http" && u.Scheme != "https" {
-			log.Fatalf("error invalid sitemap %q - scheme must be 'http' or 'https'", m.Sitemap)
-		} else if u.User != nil || u.Path != "" || u.ForceQuery || u.Fragment != "" || strings.HasSuffix(m.Sitemap, "#") {
-			log.Fatalf("error invalid sitemap %q - do not include the userinfo, path, query or fragment", m.Sitemap)
-		}
+	m, err := newMixdown(cfg)
+	if err != nil {
+		logger.Fatalf("%s", err)
 	}
 
-	log.Println("mixdown with following options;")
-	log.Printf("  -base-url     : %q", m.BaseURL)
-	log.Printf("  -outdir       : %q", m.OutDir)
-	log.Printf("  -use-epochname: %t", m.UseEpochname)
-	log.Printf("  -extname      : %q", m.Extname)
-	log.Printf("  -narchive     : %d", m.NArchive)
-	log.Printf("  -sitemap      : %q", m.Sitemap)
-
-	// remove existing output-dir
-	if err := os.RemoveAll(m.OutDir); err != nil && !os.IsNotExist(err) {
-		log.Fatalf("failed to os.RemoveAll(): %s", err)
+	logger.Infof("mixdown with following options;")
+	logger.Infof("  -base-url     : %q (%s)", m.BaseURL, m.Source("BaseURL"))
+	logger.Infof("  -outdir       : %q (%s)", m.OutDir, m.Source("OutDir"))
+	logger.Infof("  -use-epochname: %t (%s)", m.UseEpochname, m.Source("UseEpochname"))
+	logger.Infof("  -extname      : %q (%s)", m.Extname, m.Source("Extname"))
+	logger.Infof("  -narchive     : %d (%s)", m.NArchive, m.Source("NArchive"))
+	logger.Infof("  -sitemap      : %q (%s)", m.Sitemap, m.Source("Sitemap"))
+	logger.Infof("  -jobs         : %d (%s)", m.Jobs, m.Source("Jobs"))
+	logger.Infof("  -backup       : %q (%s)", m.Backup, m.Source("Backup"))
+	logger.Infof("  -watch        : %t", watch)
+	if cmd == "build" {
+		logger.Infof("  -check        : %t", postCheck)
+		logger.Infof("  -dry-run      : %t (%s)", m.DryRun, m.Source("DryRun"))
+		logger.Infof("  -force        : %t (%s)", m.Force, m.Source("Force"))
 	}
-
-	// create outdir
-	log.Println(strings.Repeat("*", 80))
-	log.Printf("CREATE OUTPUT DIRECTORY %q", m.OutDir)
-	if err := util.Mkdir(m.OutDir); err != nil {
-		log.Fatalf("failed to util.Mkdir(): %s", err)
+	if cmd == "serve" {
+		logger.Infof("  -addr         : %q", addr)
 	}
 
-	// create sitemap.txt
-	if m.Sitemap != "" {
-		pathname := filepath.Join(m.OutDir, "sitemap.txt")
-		if ofile, err := util.CreateFile(pathname); err != nil {
-			log.Fatalf("faield to util.CreateFile(): %s", err)
-		} else {
-			m.SitemapFile = ofile
-			defer ofile.Close()
-			log.Println(strings.Repeat("*", 80))
-			log.Printf("CREATE SITEMAP %q", pathname)
+	switch cmd {
+	case "serve":
+		if err := m.serve(addr, watch, cfgFiles); err != nil {
+			logger.Fatalf("failed to serve(): %s", err)
 		}
-	}
-
-	// load theme files
-	log.Println(strings.Repeat("*", 80))
-	log.Println("LOAD THEME FILES")
-	if t, err := theme.New(m.ThemeDir); err != nil {
-		log.Fatalf("failed to theme.New(): %s", err)
-	} else {
-		m.Theme = t
-	}
-
-	// load tracked files
-	log.Println(strings.Repeat("*", 80))
-	log.Println("LOAD TRACKED FILES")
-	if docs, rsrc, err := file.GetTrackedFiles(m.BaseURL, m.UseEpochname, m.Extname); err != nil {
-		log.Fatalf("failed to file.GetTrackedFiles(): %s", err)
-	} else {
-		m.Documents = docs
-		m.Resources = rsrc
-	}
-
-	// render
-	for _, target := range []string{
-		"tag", "article", "archive", "home", "resources",
-	} {
-		log.Println(strings.Repeat("*", 80))
-		log.Printf("RENDER %q", strings.Title(target))
-		if err := m.render(target); err != nil {
-			log.Fatalf("failed to render(): %s", err)
+	case "check":
+		if err := m.Check(); err != nil {
+			logger.Fatalf("failed to Check(): %s", err)
+		}
+	default:
+		if err := m.build(); err != nil {
+			logger.Fatalf("failed to build(): %s", err)
+		} else if postCheck && !m.DryRun {
+			if err = m.Check(); err != nil {
+				logger.Fatalf("failed to Check(): %s", err)
+			}
+		}
+		if watch && !m.DryRun {
+			if err := m.watch(cfgFiles, interrupted(), nil); err != nil {
+				logger.Fatalf("failed to watch(): %s", err)
+			}
 		}
 	}
 
-	// export assets
-	log.Println(strings.Repeat("*", 80))
-	log.Println("EXPORT ASSETS DIRECTORIES")
-	if err := m.Theme.ExportAssets(m.OutDir); err != nil {
-		log.Fatalf("failed to theme.ExportAssets(): %s", err)
-	}
-
-	log.Println(strings.Repeat("*", 80))
-	log.Println("goodbye")
+	logger.Infof("goodbye")
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mah0x211/mixdown/builder"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(cfgFile, []byte(`{"baseURL": "/file/", "jobs": 1, "narchive": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"MIXDOWN_JOBS": "2", "MIXDOWN_NARCHIVE": "6"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	// the command-line parameters that were set explicitly
+	cfg := builder.NewConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fields := bindFlags(fs, cfg, true)
+	if err := fs.Parse([]string{"-narchive", "7", "-dry-run"}); err != nil {
+		t.Fatal(err)
+	} else if err = loadConfig(cfg, fs, fields, []string{cfgFile}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		field string
+		got   interface{}
+		want  interface{}
+		src   string
+	}{
+		{"BaseURL", cfg.BaseURL, "/file/", "file " + cfgFile},
+		{"Jobs", cfg.Jobs, 2, "env MIXDOWN_JOBS"},
+		{"NArchive", cfg.NArchive, 7, "flag -narchive"},
+		{"DryRun", cfg.DryRun, true, "flag -dry-run"},
+		{"Extname", cfg.Extname, "html", "default"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+		} else if src := cfg.Source(c.field); src != c.src {
+			t.Errorf("Source(%q) = %q, want %q", c.field, src, c.src)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mah0x211/mixdown/builder"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/rex"
+	"github.com/mah0x211/mixdown/util"
+)
+
+// archetype that is used if the default archetype file does not exist
+const defaultArchetype = `# {{.Subject}}
+
+{{range $i, $tag := .Hashtags}}{{if $i}} {{end}}{{$tag}}{{end}}
+`
+
+// load the named archetype template
+func (m *Mixdown) loadArchetype(name string) (*template.Template, error) {
+	pathname := filepath.Join(m.ArchetypeDir, name+".md")
+	tmpl := template.New(name)
+	if buf, err := ioutil.ReadFile(pathname); err == nil {
+		logger.Debugf("archetype %q - %q", name, pathname)
+		return tmpl.Parse(string(buf))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	} else if name != "default" {
+		return nil, fmt.Errorf("archetype %q is not found", pathname)
+	}
+
+	logger.Debugf("archetype %q - built-in", name)
+	return tmpl.Parse(defaultArchetype)
+}
+
+// newPost creates a markdown file from the archetype with a subject and
+// hashtags of args, and stages it if add is true.
+func (m *Mixdown) newPost(archetype, dir, slug string, add bool, args []string) error {
+	type stPost struct {
+		Subject  string
+		Slug     string
+		Hashtags []string
+		Date     time.Time
+	}
+
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return fmt.Errorf("subject must be specified")
+	}
+	post := stPost{
+		Subject: strings.TrimSpace(args[0]),
+		Slug:    slug,
+		Date:    time.Now(),
+	}
+
+	// verify hashtags
+	for _, tag := range args[1:] {
+		if !strings.HasPrefix(tag, "#") {
+			tag = "#" + tag
+		}
+		if rex.Hashtag.FindString(tag) != tag {
+			return fmt.Errorf("invalid hashtag %q", tag)
+		}
+		post.Hashtags = append(post.Hashtags, tag)
+	}
+
+	// verify slug
+	if post.Slug == "" {
+		post.Slug = util.Slugify(post.Subject)
+	}
+	if post.Slug == "" || post.Slug != filepath.Base(post.Slug) || strings.HasPrefix(post.Slug, ".") {
+		return fmt.Errorf("invalid slug %q - specify a valid slug with -slug option", post.Slug)
+	}
+
+	// verify pathname
+	pathname := filepath.Join(dir, post.Slug+".md")
+	if strings.HasPrefix(pathname, builder.DotDir) || pathname == filepath.Clean(builder.DotDir) {
+		return fmt.Errorf("invalid dir %q - cannot be created in the %q directory", dir, builder.DotDir)
+	} else if _, err := os.Lstat(pathname); err == nil {
+		return fmt.Errorf("%q already exists", pathname)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmpl, err := m.loadArchetype(archetype)
+	if err != nil {
+		return fmt.Errorf("failed to loadArchetype(): %s", err)
+	}
+
+	// create file
+	logger.Infof("create %q", pathname)
+	if f, err := util.CreateFile(pathname); err != nil {
+		return fmt.Errorf("failed to util.CreateFile(): %s", err)
+	} else if err = tmpl.Execute(f, post); err != nil {
+		f.Close()
+		os.Remove(pathname)
+		return fmt.Errorf("failed to Template.Execute(): %s", err)
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+
+	// stage file to make it visible as tracked file
+	if add {
+		logger.Infof("git add %q", pathname)
+		if _, err := util.ExecCommand("git", "add", "--", pathname); err != nil {
+			return fmt.Errorf("failed to util.ExecCommand(): %s", err)
+		}
+	}
+
+	// hashtags are extracted from the commit message
+	if len(post.Hashtags) > 0 {
+		logger.Infof(
+			"commit with the hashtags in the message body to list it on the tag pages; git commit -m %q -m %q",
+			post.Subject, strings.Join(post.Hashtags, " "),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mah0x211/mixdown/builder"
+)
+
+// change the working directory to the temporary directory
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	} else if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestNewPost(t *testing.T) {
+	chdirTemp(t)
+	m := &Mixdown{&builder.Builder{Config: *builder.NewConfig()}}
+	if err := os.MkdirAll(m.ArchetypeDir, 0755); err != nil {
+		t.Fatal(err)
+	} else if err = ioutil.WriteFile(filepath.Join(m.ArchetypeDir, "note.md"), []byte("---\ntitle: {{.Subject}}\nslug: {{.Slug}}\ndate: {{.Date.Year}}\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		archetype string
+		dir       string
+		slug      string
+		args      []string
+		pathname  string
+		want      string
+	}{
+		{"default", ".", "", []string{"Hello, World!", "release", "#note"}, "hello-world.md", "# Hello, World!\n\n#release #note\n"},
+		{"default", "posts", "", []string{" 日本語 タイトル "}, filepath.Join("posts", "日本語-タイトル.md"), "# 日本語 タイトル\n\n\n"},
+		{"note", ".", "my-note", []string{"Note"}, "my-note.md", "---\ntitle: Note\nslug: my-note\ndate: " + strconv.Itoa(time.Now().Year()) + "\n---\n"},
+	} {
+		if err := m.newPost(c.archetype, c.dir, c.slug, false, c.args); err != nil {
+			t.Fatalf("newPost(%v): %s", c.args, err)
+		} else if buf, err := ioutil.ReadFile(c.pathname); err != nil {
+			t.Error(err)
+		} else if string(buf) != c.want {
+			t.Errorf("%s = %q, want %q", c.pathname, buf, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		archetype string
+		dir       string
+		slug      string
+		args      []string
+		errmsg    string
+	}{
+		{"default", ".", "", []string{"Hello World"}, "already exists"},
+		{"default", ".", "", nil, "subject must be specified"},
+		{"default", ".", "", []string{" "}, "subject must be specified"},
+		{"default", ".", "", []string{"!?"}, "invalid slug"},
+		{"default", ".", "../x", []string{"x"}, "invalid slug"},
+		{"default", ".", ".x", []string{"x"}, "invalid slug"},
+		{"default", ".", "", []string{"x", "#a b"}, "invalid hashtag"},
+		{"default", builder.DotDir, "", []string{"x"}, "cannot be created"},
+		{"missing", ".", "", []string{"x"}, "is not found"},
+	} {
+		if err := m.newPost(c.archetype, c.dir, c.slug, false, c.args); err == nil || !strings.Contains(err.Error(), c.errmsg) {
+			t.Errorf("newPost(%q, %q, %q, %v) returns %v, want %q", c.archetype, c.dir, c.slug, c.args, err, c.errmsg)
+		}
+	}
+
+	// the existing file is not overwritten
+	if buf, err := ioutil.ReadFile("hello-world.md"); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(string(buf), "# Hello, World!") {
+		t.Errorf("hello-world.md is overwritten: %q", buf)
+	}
+	if _, err := os.Stat("x.md"); err == nil {
+		t.Error("x.md is created by the failed newPost()")
+	}
+}
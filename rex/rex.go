@@ -42,10 +42,22 @@ var (
 		`\B#[^ \f\n\r\t\v` + "\u00a0\u1680\u2000-\u200a\u2028\u2029\u202f\u205f\u3000\ufeff" + `]+`,
 	)
 
+	// Language is pattern of language tags, e.g. en or ja-JP
+	Language = regexp.MustCompile(`^[a-zA-Z]{2,8}(?:-[a-zA-Z0-9]{1,8})*$`)
+
 	// ThemeFile is pattern of names of theme-file
 	ThemeFile = regexp.MustCompile(
-		// <fname>.page.<ext>[@<fname>.<ext>]
-		`^(\w+(?:\.\w+)*)\.mix\.\w+(?:@(\w+(?:\.\w+)+))?$`,
+		// <fname>.mix.<ext>[@<fname>.<ext>]
+		`^(\w+(?:\.\w+)*)\.mix\.(\w+)(?:@(\w+(?:\.\w+)+))?$`,
+	)
+
+	// HTMLComment is pattern of comments in HTML
+	HTMLComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+	// HTMLAttr is pattern of attributes that refer to URL or define anchor
+	HTMLAttr = regexp.MustCompile(
+		// <tag name="value">, <tag name='value'> or <tag name=value>
+		`(?i)\s(href|src|srcset|id|name)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+))`,
 	)
 
 	// TemplateAction is pattern of sub-template directive
@@ -0,0 +1,87 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/server"
+)
+
+// serve builds the site into outdir and serves it until interrupted.
+// if watch is true, it rebuilds the site on changes and reloads the pages
+// opened in browsers.
+func (m *Mixdown) serve(addr string, watch bool, cfgFiles []string) error {
+	defer os.RemoveAll(m.OutDir)
+
+	if err := m.build(); err != nil {
+		return err
+	}
+
+	h, err := server.New(m.OutDir, m.BaseURL, m.Extname)
+	if err != nil {
+		return err
+	}
+	// live reload must be enabled before the server handles the requests
+	if watch {
+		h.EnableLiveReload()
+	}
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: h,
+	}
+
+	stop := interrupted()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	logger.Infof("serve %q on %q", m.OutDir, addr)
+
+	// watch changes until the server stops
+	watchc := make(chan error, 1)
+	watchStop := make(chan struct{})
+	if watch {
+		go func() {
+			watchc <- m.watch(cfgFiles, watchStop, h.Reload)
+		}()
+	} else {
+		watchc <- nil
+	}
+
+	select {
+	case err = <-errc:
+	case <-stop:
+		err = srv.Close()
+	}
+	close(watchStop)
+	if werr := <-watchc; err == nil {
+		err = werr
+	}
+
+	return err
+}
@@ -0,0 +1,255 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LiveReloadPath is the path of the server-sent events endpoint under the
+// path of base URL
+const LiveReloadPath = "_mixdown/livereload"
+
+// snippet of live reload that will be injected into the pages
+const liveReloadSnippet = `<script>(function(){` +
+	`var es = new EventSource(%q);` +
+	`es.addEventListener("reload", function(){ es.close(); location.reload(); });` +
+	`})();</script>`
+
+// Server is the http.Handler that serves the files of the output directory
+// under the path of base URL
+type Server struct {
+	rootdir    string
+	prefix     string
+	extname    string
+	livereload bool
+	mu         sync.Mutex
+	clients    map[chan struct{}]struct{}
+}
+
+// New allocate a instance of Server
+func New(rootdir, baseURL, extname string) (*Server, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// use only the path of base URL as a prefix
+	prefix := path.Clean("/" + u.Path)
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	return &Server{
+		rootdir: rootdir,
+		prefix:  prefix,
+		extname: "." + extname,
+		clients: make(map[chan struct{}]struct{}),
+	}, nil
+}
+
+// EnableLiveReload injects the live reload snippet into the pages
+func (s *Server) EnableLiveReload() {
+	s.livereload = true
+}
+
+// Reload pushes the reload event to the connected browsers
+func (s *Server) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// serveEvents sends the reload event as server-sent events
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// injectLiveReload inserts the live reload snippet before the closing body
+// tag of the page
+func (s *Server) injectLiveReload(page []byte) []byte {
+	snippet := []byte(fmt.Sprintf(liveReloadSnippet, s.prefix+LiveReloadPath))
+	idx := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if idx < 0 {
+		return append(page, snippet...)
+	}
+
+	buf := make([]byte, 0, len(page)+len(snippet))
+	buf = append(buf, page[:idx]...)
+	buf = append(buf, snippet...)
+	return append(buf, page[idx:]...)
+}
+
+// open the file of pathname and returns it with its file information
+func open(pathname string) (*os.File, os.FileInfo, error) {
+	f, err := os.Open(pathname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// serveFile writes the content of f to w with the content-type of its
+// extension
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, f *os.File, modtime time.Time, status int) {
+	var content io.ReadSeeker = f
+	ext := filepath.Ext(f.Name())
+	if ext == s.extname {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if s.livereload {
+			page, err := ioutil.ReadAll(f)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			content = bytes.NewReader(s.injectLiveReload(page))
+			// disable caching to reload the latest page
+			w.Header().Set("Cache-Control", "no-store")
+			modtime = time.Time{}
+		}
+	} else if ctype := mime.TypeByExtension(ext); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	if status == http.StatusOK {
+		http.ServeContent(w, r, f.Name(), modtime, content)
+		return
+	}
+
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		io.Copy(w, content)
+	}
+}
+
+// notFound writes the custom 404 page if it exists in the root directory
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
+	f, info, err := open(filepath.Join(s.rootdir, "404"+s.extname))
+	if err != nil || info.IsDir() {
+		if f != nil {
+			f.Close()
+		}
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	s.serveFile(w, r, f, info.ModTime(), http.StatusNotFound)
+}
+
+// ServeHTTP responds to the request with the file of request path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	upath := r.URL.Path
+	if s.livereload && upath == s.prefix+LiveReloadPath {
+		s.serveEvents(w, r)
+		return
+	} else if upath+"/" == s.prefix {
+		http.Redirect(w, r, s.prefix, http.StatusMovedPermanently)
+		return
+	} else if !strings.HasPrefix(upath, s.prefix) {
+		s.notFound(w, r)
+		return
+	}
+
+	// resolve pathname in the root directory
+	rel := path.Clean("/" + strings.TrimPrefix(upath, s.prefix))
+	pathname := filepath.Join(s.rootdir, filepath.FromSlash(rel))
+	if info, err := os.Stat(pathname); err == nil && info.IsDir() {
+		// resolve directory index
+		if !strings.HasSuffix(upath, "/") {
+			http.Redirect(w, r, upath+"/", http.StatusMovedPermanently)
+			return
+		}
+		pathname = filepath.Join(pathname, "index"+s.extname)
+	}
+
+	f, info, err := open(pathname)
+	if err != nil || info.IsDir() {
+		if f != nil {
+			f.Close()
+		}
+		s.notFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	s.serveFile(w, r, f, info.ModTime(), http.StatusOK)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// create the output directory with the files of the pathnames and contents
+func writeTestRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		pathname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		} else if err = ioutil.WriteFile(pathname, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestServeHTTP(t *testing.T) {
+	root := writeTestRoot(t, map[string]string{
+		"index.html":       "<body>home</body>",
+		"404.html":         "<body>custom 404</body>",
+		"archive/1.html":   "<body>archive</body>",
+		"assets/style.css": "body{}",
+	})
+	s, err := New(root, "/blog", "html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		method   string
+		path     string
+		status   int
+		location string
+		body     string
+		ctype    string
+	}{
+		{"GET", "/blog/", 200, "", "<body>home</body>", "text/html; charset=utf-8"},
+		{"GET", "/blog/index.html", 200, "", "<body>home</body>", "text/html; charset=utf-8"},
+		{"GET", "/blog/archive/1.html", 200, "", "<body>archive</body>", "text/html; charset=utf-8"},
+		{"GET", "/blog/assets/style.css", 200, "", "body{}", "text/css; charset=utf-8"},
+		{"HEAD", "/blog/", 200, "", "", "text/html; charset=utf-8"},
+		// slash redirects
+		{"GET", "/blog", 301, "/blog/", "", ""},
+		{"GET", "/blog/archive", 301, "/blog/archive/", "", ""},
+		// custom 404 page
+		{"GET", "/blog/missing.html", 404, "", "<body>custom 404</body>", "text/html; charset=utf-8"},
+		{"GET", "/blog/archive/", 404, "", "<body>custom 404</body>", "text/html; charset=utf-8"},
+		{"GET", "/index.html", 404, "", "<body>custom 404</body>", "text/html; charset=utf-8"},
+		{"HEAD", "/blog/missing.html", 404, "", "", "text/html; charset=utf-8"},
+		// outside of the root directory
+		{"GET", "/blog/../../" + filepath.Base(root) + "/index.html", 404, "", "<body>custom 404</body>", "text/html; charset=utf-8"},
+		{"POST", "/blog/", 405, "", "Method Not Allowed\n", "text/plain; charset=utf-8"},
+		// live reload is disabled
+		{"GET", "/blog/" + LiveReloadPath, 404, "", "<body>custom 404</body>", "text/html; charset=utf-8"},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s = %d, want %d", c.method, c.path, w.Code, c.status)
+		} else if loc := w.Header().Get("Location"); loc != c.location {
+			t.Errorf("%s %s redirects to %q, want %q", c.method, c.path, loc, c.location)
+		} else if c.status != 301 && w.Body.String() != c.body {
+			t.Errorf("%s %s = %q, want %q", c.method, c.path, w.Body.String(), c.body)
+		} else if ctype := w.Header().Get("Content-Type"); c.ctype != "" && ctype != c.ctype {
+			t.Errorf("%s %s content-type = %q, want %q", c.method, c.path, ctype, c.ctype)
+		}
+	}
+
+	// the default 404 page
+	if err = os.Remove(filepath.Join(root, "404.html")); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/blog/missing.html", nil))
+	if w.Code != 404 || w.Body.String() != "404 page not found\n" {
+		t.Errorf("GET /blog/missing.html = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestServeHTTPRootPrefix(t *testing.T) {
+	root := writeTestRoot(t, map[string]string{"index.html": "home"})
+	for _, baseURL := range []string{"/", "", "https://example.com"} {
+		s, err := New(root, baseURL, "html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 200 || w.Body.String() != "home" {
+			t.Errorf("GET / with base URL %q = %d %q", baseURL, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestLiveReload(t *testing.T) {
+	root := writeTestRoot(t, map[string]string{
+		"index.html":       "<html><BODY>home</BODY></html>",
+		"nobody.html":      "fragment",
+		"assets/style.css": "body{}",
+	})
+	s, err := New(root, "/blog/", "html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.EnableLiveReload()
+
+	snippet := `new EventSource("/blog/` + LiveReloadPath + `")`
+	for _, c := range []struct {
+		path   string
+		prefix string
+		suffix string
+		inject bool
+	}{
+		{"/blog/", "<html><BODY>home<script>", "</script></BODY></html>", true},
+		{"/blog/nobody.html", "fragment<script>", "</script>", true},
+		{"/blog/assets/style.css", "body{}", "body{}", false},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		body := w.Body.String()
+		if w.Code != 200 || !strings.HasPrefix(body, c.prefix) || !strings.HasSuffix(body, c.suffix) ||
+			strings.Contains(body, snippet) != c.inject {
+			t.Errorf("GET %s = %d %q", c.path, w.Code, body)
+		} else if c.inject && w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("GET %s is cached", c.path)
+		}
+	}
+
+	// reload event is pushed to the connected browser
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/blog/" + LiveReloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ctype := res.Header.Get("Content-Type"); ctype != "text/event-stream" {
+		t.Fatalf("content-type of events = %q", ctype)
+	}
+
+	events := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(res.Body).ReadString('\n')
+		events <- line
+	}()
+	s.Reload()
+	select {
+	case line := <-events:
+		if line != "event: reload\n" {
+			t.Errorf("event = %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload event is not received")
+	}
+}
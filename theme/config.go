@@ -0,0 +1,159 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package theme
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/util"
+	"gopkg.in/yaml.v3"
+)
+
+// configExts is the extensions of the theme config file "theme.<ext>"
+var configExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// config is the declaration of the theme in the theme config file
+type config struct {
+	// Parent is the directory of the parent theme relative to the theme
+	// directory. the templates, partials and asset files that are missing in
+	// the theme are looked up in the parent theme.
+	Parent string `json:"parent" yaml:"parent" toml:"parent"`
+}
+
+// themeDir is the directory of the theme files
+type themeDir struct {
+	// pathname of the directory that is the prefix of the pathnames of files
+	name string
+	fsys fs.FS
+	// true if it is the default theme embedded in the binary
+	embedded bool
+}
+
+// returns the pathname of the file of the slash-separated name
+func (d *themeDir) pathname(name string) string {
+	return filepath.Join(d.name, filepath.FromSlash(name))
+}
+
+// readConfig reads the theme config file in the directory. it returns the
+// empty config if the theme has no config file.
+func (d *themeDir) readConfig() (*config, error) {
+	cfg := &config{}
+	for _, ext := range configExts {
+		buf, err := fs.ReadFile(d.fsys, "theme"+ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch ext {
+		case ".json":
+			err = json.Unmarshal(buf, cfg)
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(buf, cfg)
+		case ".toml":
+			err = toml.Unmarshal(buf, cfg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %q: %s", d.pathname("theme"+ext), err)
+		}
+		break
+	}
+
+	return cfg, nil
+}
+
+// returns themedir and the directories of its parent themes in order of
+// precedence, or the default theme if themedir does not exist
+func lookupDirs(themedir string) ([]*themeDir, error) {
+	dirs := []*themeDir{}
+	for dir := filepath.Clean(themedir); dir != ""; {
+		for i, v := range dirs {
+			if v.name == dir {
+				chain := []string{}
+				for _, parent := range dirs[i:] {
+					chain = append(chain, parent.name)
+				}
+				chain = append(chain, dir)
+				return nil, fmt.Errorf("error theme %q inherits itself: %s", dir, strings.Join(chain, " -> "))
+			}
+		}
+
+		// verify theme directory
+		if ok, err := util.IsDir(dir); err != nil {
+			return nil, err
+		} else if !ok {
+			if len(dirs) > 0 {
+				return nil, fmt.Errorf("parent theme %q of %q is not found", dir, dirs[len(dirs)-1].name)
+			}
+			logger.Infof("theme %q is not found, use the default theme", dir)
+			return []*themeDir{defaultDir()}, nil
+		}
+		d := &themeDir{
+			name: dir,
+			fsys: os.DirFS(dir),
+		}
+		dirs = append(dirs, d)
+
+		cfg, err := d.readConfig()
+		if err != nil {
+			return nil, err
+		} else if cfg.Parent == "" {
+			break
+		} else if filepath.IsAbs(cfg.Parent) {
+			dir = filepath.Clean(cfg.Parent)
+		} else {
+			dir = filepath.Join(dir, cfg.Parent)
+		}
+	}
+
+	return dirs, nil
+}
+
+// LookupDirs returns themedir and the directories of its parent themes in
+// order of precedence. it returns the empty list if themedir does not exist
+// and the default theme is used.
+func LookupDirs(themedir string) ([]string, error) {
+	dirs, err := lookupDirs(themedir)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if !d.embedded {
+			list = append(list, d.name)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package theme
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/util"
+)
+
+//go:embed default
+var defaultFS embed.FS
+
+// defaultName is the pathname of the default theme in the error messages
+const defaultName = "<default>"
+
+// returns the default theme embedded in the binary
+func defaultDir() *themeDir {
+	fsys, err := fs.Sub(defaultFS, "default")
+	if err != nil {
+		panic(err)
+	}
+	return &themeDir{
+		name:     defaultName,
+		fsys:     fsys,
+		embedded: true,
+	}
+}
+
+// ExportDefault writes the files of the default theme into themedir.
+// the existing files are not overwritten.
+func ExportDefault(themedir string) error {
+	return fs.WalkDir(defaultFS, "default", func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel("default", filepath.FromSlash(pathname))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(themedir, rel)
+		if _, err = os.Lstat(dst); err == nil {
+			logger.Infof("skip existing file %q", dst)
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		buf, err := defaultFS.ReadFile(pathname)
+		if err != nil {
+			return err
+		}
+		f, err := util.CreateFile(dst)
+		if err != nil {
+			return err
+		}
+		logger.Infof("create %q", dst)
+		if _, err = f.Write(buf); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
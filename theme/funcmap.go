@@ -73,12 +73,37 @@ func fnSlice(arg reflect.Value, s, e int) (reflect.Value, error) {
 	}
 }
 
-func fnEscapePath(str string) string {
-	return url.PathEscape(str)
+func fnEscapePath(v interface{}) string {
+	return url.PathEscape(toString(v))
 }
 
 var defaultFuncMap = template.FuncMap{
 	"indirect":   fnIndirect,
 	"slice":      fnSlice,
 	"escapePath": fnEscapePath,
+	// string and HTML helpers
+	"truncate":      fnTruncate,
+	"truncateWords": fnTruncateWords,
+	"stripHTML":     fnStripHTML,
+	"plainify":      fnPlainify,
+	"markdownify":   fnMarkdownify,
+	"replace":       fnReplace,
+	"regexReplace":  fnRegexReplace,
+	"title":         fnTitle,
+	"upper":         fnUpper,
+	"lower":         fnLower,
+	"urlize":        fnUrlize,
+	"jsonify":       fnJSONify,
+	"xml":           fnXML,
+	// collection helpers
+	"where":     fnWhere,
+	"sortBy":    fnSortBy,
+	"first":     fnFirst,
+	"last":      fnLast,
+	"after":     fnAfter,
+	"uniq":      fnUniq,
+	"union":     fnUnion,
+	"intersect": fnIntersect,
+	"dict":      fnDict,
+	"list":      fnList,
 }
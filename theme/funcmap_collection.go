@@ -0,0 +1,562 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package theme
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// returns v as the list that can be iterated and sliced. the array is copied
+// to the slice since the unaddressable array can't be sliced.
+func toList(arg reflect.Value) (reflect.Value, error) {
+	v := indirectInterface(arg)
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("iterate over untyped nil")
+	}
+
+	switch v.Kind() {
+	case reflect.Array:
+		if v.CanAddr() {
+			return v, nil
+		}
+		list := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+		reflect.Copy(list, v)
+		return list, nil
+	case reflect.Slice:
+		return v, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("can't iterate over %s", v.Type())
+	}
+}
+
+// returns an empty slice that can hold the items of list
+func newList(list reflect.Value, n int) reflect.Value {
+	typ := list.Type()
+	if typ.Kind() == reflect.Array {
+		typ = reflect.SliceOf(typ.Elem())
+	}
+	return reflect.MakeSlice(typ, 0, n)
+}
+
+// returns the value of the dot-separated key, e.g. "Newer.Subject", in the
+// fields of struct or the items of map. it returns the invalid value if the
+// item of map does not exist.
+func lookupField(v reflect.Value, key string) (reflect.Value, error) {
+	if key == "" {
+		return indirectInterface(v), nil
+	}
+
+	for _, name := range strings.Split(key, ".") {
+		v = indirectInterface(v)
+		for v.IsValid() && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = indirectInterface(v.Elem())
+		}
+		if !v.IsValid() {
+			return v, nil
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || f.PkgPath != "" {
+				return reflect.Value{}, fmt.Errorf("%q is not a field of %s", name, v.Type())
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("can't lookup %q in %s", name, v.Type())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}, fmt.Errorf("can't lookup %q in %s", name, v.Type())
+		}
+	}
+
+	return indirectInterface(v), nil
+}
+
+// compare a and b, and returns -1, 0 or +1. numbers, strings, bools and times
+// can be compared.
+func compare(a, b reflect.Value) (int, error) {
+	a, b = indirectInterface(a), indirectInterface(b)
+	if !a.IsValid() || !b.IsValid() {
+		switch {
+		case a.IsValid():
+			return 1, nil
+		case b.IsValid():
+			return -1, nil
+		}
+		return 0, nil
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	} else if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), nil
+	} else if a.Kind() == reflect.Bool && b.Kind() == reflect.Bool {
+		switch x, y := a.Bool(), b.Bool(); {
+		case x == y:
+			return 0, nil
+		case y:
+			return -1, nil
+		}
+		return 1, nil
+	} else if a.Type() == timeType && b.Type() == timeType {
+		x, y := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case x.Before(y):
+			return -1, nil
+		case x.After(y):
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("can't compare %s with %s", a.Type(), b.Type())
+}
+
+// returns the number as float64
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// returns true if a equals to b. pointers are equal if they point to the same
+// value.
+func equal(a, b reflect.Value) bool {
+	a, b = indirectInterface(a), indirectInterface(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	} else if a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr {
+		return a.Type() == b.Type() && a.Pointer() == b.Pointer()
+	} else if n, err := compare(a, b); err == nil {
+		return n == 0
+	} else if a.Type() != b.Type() {
+		return false
+	} else if a.Type().Comparable() {
+		return a.Interface() == b.Interface()
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// returns true if list contains v. the string contains v if v is a substring
+// of it.
+func contains(list, v reflect.Value) (bool, error) {
+	list = indirectInterface(list)
+	if !list.IsValid() {
+		return false, nil
+	} else if list.Kind() == reflect.String {
+		s := indirectInterface(v)
+		if !s.IsValid() || s.Kind() != reflect.String {
+			return false, fmt.Errorf("can't search %s in string", v.Type())
+		}
+		return strings.Contains(list.String(), s.String()), nil
+	} else if list.Kind() != reflect.Array && list.Kind() != reflect.Slice {
+		return false, fmt.Errorf("can't search in %s", list.Type())
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		if equal(list.Index(i), v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// returns true if the value of the field matches v with the operator
+func match(field reflect.Value, op string, v reflect.Value) (bool, error) {
+	switch op {
+	case "=", "==", "eq":
+		return equal(field, v), nil
+	case "!=", "<>", "ne":
+		return !equal(field, v), nil
+	case "in":
+		return contains(v, field)
+	case "not in":
+		ok, err := contains(v, field)
+		return !ok, err
+	case "contains":
+		return contains(field, v)
+	case "not contains":
+		ok, err := contains(field, v)
+		return !ok, err
+	}
+
+	n, err := compare(field, v)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "<", "lt":
+		return n < 0, nil
+	case "<=", "le":
+		return n <= 0, nil
+	case ">", "gt":
+		return n > 0, nil
+	case ">=", "ge":
+		return n >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// fnWhere returns the items of the list whose field of key matches the value
+// with the operator, e.g. `where "Hashtags" "contains" "#release" .Documents`.
+// the operator can be omitted to compare with "==".
+func fnWhere(key string, args ...interface{}) (reflect.Value, error) {
+	op := "=="
+	switch len(args) {
+	case 2:
+	case 3:
+		s, ok := args[0].(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("operator must be string: %#v", args[0])
+		}
+		op, args = s, args[1:]
+	default:
+		return reflect.Value{}, fmt.Errorf("wrong number of args for where: want 3 or 4 got %d", len(args)+1)
+	}
+
+	list, err := toList(reflect.ValueOf(args[1]))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v := reflect.ValueOf(args[0])
+	res := newList(list, 0)
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i)
+		field, err := lookupField(item, key)
+		if err != nil {
+			return reflect.Value{}, err
+		} else if ok, err := match(field, op, v); err != nil {
+			return reflect.Value{}, fmt.Errorf("where %q %q: %s", key, op, err)
+		} else if ok {
+			res = reflect.Append(res, item)
+		}
+	}
+
+	return res, nil
+}
+
+// fnSortBy returns the copy of the list sorted by the field of key in "asc"
+// or "desc" order, e.g. `sortBy "Cdate" "desc" .Documents`. the items are
+// sorted by their own values if key is empty.
+func fnSortBy(key string, args ...interface{}) (reflect.Value, error) {
+	desc := false
+	switch len(args) {
+	case 1:
+	case 2:
+		switch args[0] {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return reflect.Value{}, fmt.Errorf("order must be \"asc\" or \"desc\": %#v", args[0])
+		}
+		args = args[1:]
+	default:
+		return reflect.Value{}, fmt.Errorf("wrong number of args for sortBy: want 2 or 3 got %d", len(args)+1)
+	}
+
+	list, err := toList(reflect.ValueOf(args[0]))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	n := list.Len()
+	res := reflect.AppendSlice(newList(list, n), list.Slice(0, n))
+	fields := make([]reflect.Value, n)
+	for i := range fields {
+		if fields[i], err = lookupField(res.Index(i), key); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	// sort the items with the fields
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		c, cerr := compare(fields[idx[i]], fields[idx[j]])
+		if cerr != nil && err == nil {
+			err = fmt.Errorf("sortBy %q: %s", key, cerr)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	sorted := newList(list, n)
+	for _, i := range idx {
+		sorted = reflect.Append(sorted, res.Index(i))
+	}
+	return sorted, nil
+}
+
+// group is the items that have the same key
+type group struct {
+	Key   interface{}
+	Items interface{}
+}
+
+// layouts of the date parts for groupBy
+var dateParts = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+}
+
+// groupBy returns the list of groups of the items with the field of key in
+// the order of their appearance, e.g. `groupBy "Cdate" "year" .Documents`.
+// the date part is "year", "month", "day" or the layout of dateFormat, and
+// the item belongs to every group of the items if the field is a list, e.g.
+// Hashtags. the item whose date is missing or zero is not in any group.
+func (fn *dateFuncs) groupBy(key string, args ...interface{}) ([]*group, error) {
+	layout := ""
+	switch len(args) {
+	case 1:
+	case 2:
+		part, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("date part must be string: %#v", args[0])
+		} else if layout, ok = dateParts[part]; !ok {
+			layout = part
+		}
+		args = args[1:]
+	default:
+		return nil, fmt.Errorf("wrong number of args for groupBy: want 2 or 3 got %d", len(args)+1)
+	}
+
+	list, err := toList(reflect.ValueOf(args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*group
+	items := make(map[interface{}]reflect.Value)
+	add := func(k reflect.Value, item reflect.Value) error {
+		var kv interface{}
+		if layout != "" {
+			if !k.IsValid() || k.IsZero() {
+				return nil
+			}
+			s, err := fn.dateFormat(layout, k.Interface())
+			if err != nil {
+				return fmt.Errorf("groupBy %q: %s", key, err)
+			}
+			kv = s
+		} else if k.IsValid() {
+			if !k.Type().Comparable() {
+				return fmt.Errorf("groupBy %q: can't group by %s", key, k.Type())
+			}
+			kv = k.Interface()
+		}
+
+		if _, ok := items[kv]; !ok {
+			groups = append(groups, &group{Key: kv})
+			items[kv] = newList(list, 0)
+		}
+		items[kv] = reflect.Append(items[kv], item)
+		return nil
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i)
+		field, err := lookupField(item, key)
+		if err != nil {
+			return nil, err
+		}
+		if field.IsValid() && (field.Kind() == reflect.Slice || field.Kind() == reflect.Array) && field.Type() != timeType {
+			for j := 0; j < field.Len(); j++ {
+				if err = add(indirectInterface(field.Index(j)), item); err != nil {
+					return nil, err
+				}
+			}
+		} else if err = add(field, item); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range groups {
+		g.Items = items[g.Key].Interface()
+	}
+	return groups, nil
+}
+
+// fnFirst returns the first n items of the list
+func fnFirst(n int, arg reflect.Value) (reflect.Value, error) {
+	list, err := toList(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	} else if n < 0 {
+		return reflect.Value{}, fmt.Errorf("invalid number of items: %d", n)
+	} else if n > list.Len() {
+		n = list.Len()
+	}
+	return list.Slice(0, n), nil
+}
+
+// fnLast returns the last n items of the list
+func fnLast(n int, arg reflect.Value) (reflect.Value, error) {
+	list, err := toList(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	} else if n < 0 {
+		return reflect.Value{}, fmt.Errorf("invalid number of items: %d", n)
+	} else if n > list.Len() {
+		n = list.Len()
+	}
+	return list.Slice(list.Len()-n, list.Len()), nil
+}
+
+// fnAfter returns the items of the list after the first n items
+func fnAfter(n int, arg reflect.Value) (reflect.Value, error) {
+	list, err := toList(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	} else if n < 0 {
+		return reflect.Value{}, fmt.Errorf("invalid number of items: %d", n)
+	} else if n > list.Len() {
+		n = list.Len()
+	}
+	return list.Slice(n, list.Len()), nil
+}
+
+// append the items of list that are not in res
+func appendUniq(res, list reflect.Value) reflect.Value {
+	for i := 0; i < list.Len(); i++ {
+		if ok, _ := contains(res, list.Index(i)); !ok {
+			res = reflect.Append(res, list.Index(i))
+		}
+	}
+	return res
+}
+
+// returns an empty slice that can hold the items of a and b
+func newUnionList(a, b reflect.Value) reflect.Value {
+	if a.Type().Elem() == b.Type().Elem() {
+		return newList(a, 0)
+	}
+	return reflect.ValueOf([]interface{}{})
+}
+
+// fnUniq returns the list without the duplicated items
+func fnUniq(arg reflect.Value) (reflect.Value, error) {
+	list, err := toList(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return appendUniq(newList(list, 0), list), nil
+}
+
+// fnUnion returns the items of a and b without the duplicated items
+func fnUnion(a, b reflect.Value) (reflect.Value, error) {
+	x, err := toList(a)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	y, err := toList(b)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return appendUniq(appendUniq(newUnionList(x, y), x), y), nil
+}
+
+// fnIntersect returns the items of a that are also in b without the
+// duplicated items
+func fnIntersect(a, b reflect.Value) (reflect.Value, error) {
+	x, err := toList(a)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	y, err := toList(b)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	res := newUnionList(x, y)
+	for i := 0; i < x.Len(); i++ {
+		item := x.Index(i)
+		if ok, _ := contains(y, item); !ok {
+			continue
+		} else if ok, _ = contains(res, item); !ok {
+			res = reflect.Append(res, item)
+		}
+	}
+	return res, nil
+}
+
+// fnDict returns the map of the pairs of key and value, e.g.
+// `dict "title" .Subject "items" .Documents`
+func fnDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requires pairs of key and value")
+	}
+
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("key of dict must be string: %#v", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// fnList returns the list of the values
+func fnList(v ...interface{}) []interface{} {
+	if v == nil {
+		return []interface{}{}
+	}
+	return v
+}
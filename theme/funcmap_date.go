@@ -0,0 +1,377 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package theme
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// names of months and days of the week in a language
+type dateNames struct {
+	months      [12]string
+	shortMonths [12]string
+	days        [7]string
+	shortDays   [7]string
+	am, pm      string
+	now         string
+	ago         func(n int, unit string) string
+	later       func(n int, unit string) string
+}
+
+var dateNamesEn = &dateNames{
+	months: [12]string{
+		"January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December",
+	},
+	shortMonths: [12]string{
+		"Jan", "Feb", "Mar", "Apr", "May", "Jun",
+		"Jul", "Aug", "Sep", "Oct", "Nov", "Dec",
+	},
+	days: [7]string{
+		"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday",
+		"Saturday",
+	},
+	shortDays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	am:        "AM",
+	pm:        "PM",
+	now:       "just now",
+	ago: func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s ago", n, unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	},
+	later: func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("in %d %s", n, unit)
+		}
+		return fmt.Sprintf("in %d %ss", n, unit)
+	},
+}
+
+var dateUnitsJa = map[string]string{
+	"second": "秒",
+	"minute": "分",
+	"hour":   "時間",
+	"day":    "日",
+	"month":  "か月",
+	"year":   "年",
+}
+
+var dateNamesJa = &dateNames{
+	months: [12]string{
+		"1月", "2月", "3月", "4月", "5月", "6月",
+		"7月", "8月", "9月", "10月", "11月", "12月",
+	},
+	shortMonths: [12]string{
+		"1月", "2月", "3月", "4月", "5月", "6月",
+		"7月", "8月", "9月", "10月", "11月", "12月",
+	},
+	days: [7]string{
+		"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日",
+	},
+	shortDays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	am:        "午前",
+	pm:        "午後",
+	now:       "たった今",
+	ago: func(n int, unit string) string {
+		return fmt.Sprintf("%d%s前", n, dateUnitsJa[unit])
+	},
+	later: func(n int, unit string) string {
+		return fmt.Sprintf("%d%s後", n, dateUnitsJa[unit])
+	},
+}
+
+// returns the names of lang, or english names if lang is not supported
+func lookupDateNames(lang string) *dateNames {
+	if i := strings.IndexAny(lang, "-_"); i != -1 {
+		lang = lang[:i]
+	}
+	switch strings.ToLower(lang) {
+	case "ja":
+		return dateNamesJa
+	default:
+		return dateNamesEn
+	}
+}
+
+// layouts of the string values of time
+var timeLayouts = []string{
+	// TrackedFile.Cdate
+	"20060102T150405Z",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// dateFuncs formats the time in the location with the names of the language
+type dateFuncs struct {
+	loc   *time.Location
+	names *dateNames
+	now   func() time.Time
+}
+
+// newDateFuncMap returns the date functions that convert the time to loc, and
+// localize the names of months and days with lang
+func newDateFuncMap(loc *time.Location, lang string) template.FuncMap {
+	if loc == nil {
+		loc = time.Local
+	}
+	fn := &dateFuncs{
+		loc:   loc,
+		names: lookupDateNames(lang),
+		now:   time.Now,
+	}
+
+	return template.FuncMap{
+		"toTime":     fn.toTime,
+		"inTimezone": fn.inTimezone,
+		"dateFormat": fn.dateFormat,
+		"strftime":   fn.strftime,
+		"timeAgo":    fn.timeAgo,
+		"iso8601":    fn.iso8601,
+		"rfc3339":    fn.rfc3339,
+		"groupBy":    fn.groupBy,
+	}
+}
+
+// toTime converts v to the time in the location. v is the time, the epoch
+// time in seconds, or the string in the format of timeLayouts or epoch time,
+// e.g. .Cdate and .Ctime of TrackedFile.
+func (fn *dateFuncs) toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case zonedTime:
+		return t.Time, nil
+	case time.Time:
+		return t.In(fn.loc), nil
+	case *time.Time:
+		if t != nil {
+			return t.In(fn.loc), nil
+		}
+	case int:
+		return time.Unix(int64(t), 0).In(fn.loc), nil
+	case int64:
+		return time.Unix(t, 0).In(fn.loc), nil
+	case float64:
+		return time.Unix(int64(t), 0).In(fn.loc), nil
+	case string:
+		t = strings.TrimSpace(t)
+		if epoch, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return time.Unix(epoch, 0).In(fn.loc), nil
+		}
+		for _, layout := range timeLayouts {
+			loc := fn.loc
+			if strings.HasSuffix(layout, "Z") {
+				loc = time.UTC
+			}
+			if tm, err := time.ParseInLocation(layout, t, loc); err == nil {
+				return tm.In(fn.loc), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as time", t)
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %T to time", v)
+}
+
+// zonedTime is the time in the location specified by inTimezone, it is not
+// converted to the location of the site by the other functions
+type zonedTime struct {
+	time.Time
+}
+
+// inTimezone converts v to the time in the location of name, e.g. "UTC" or
+// "Asia/Tokyo"
+func (fn *dateFuncs) inTimezone(name string, v interface{}) (zonedTime, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return zonedTime{}, err
+	}
+	t, err := fn.toTime(v)
+	if err != nil {
+		return zonedTime{}, err
+	}
+	return zonedTime{t.In(loc)}, nil
+}
+
+// localized tokens of the go layout
+var layoutNames = []string{"January", "Jan", "Monday", "Mon", "PM", "pm"}
+
+// dateFormat formats v with the go layout, e.g. "2006-01-02"
+func (fn *dateFuncs) dateFormat(layout string, v interface{}) (string, error) {
+	t, err := fn.toTime(v)
+	if err != nil {
+		return "", err
+	} else if fn.names == dateNamesEn {
+		return t.Format(layout), nil
+	}
+
+	// replace names with localized ones
+	var b strings.Builder
+	for len(layout) > 0 {
+		i, token := len(layout), ""
+		for _, name := range layoutNames {
+			if j := strings.Index(layout, name); j != -1 && (j < i || j == i && len(name) > len(token)) {
+				i, token = j, name
+			}
+		}
+		b.WriteString(t.Format(layout[:i]))
+		switch token {
+		case "January":
+			b.WriteString(fn.names.months[t.Month()-1])
+		case "Jan":
+			b.WriteString(fn.names.shortMonths[t.Month()-1])
+		case "Monday":
+			b.WriteString(fn.names.days[t.Weekday()])
+		case "Mon":
+			b.WriteString(fn.names.shortDays[t.Weekday()])
+		case "PM", "pm":
+			if t.Hour() < 12 {
+				b.WriteString(fn.names.am)
+			} else {
+				b.WriteString(fn.names.pm)
+			}
+		}
+		layout = layout[i+len(token):]
+	}
+
+	return b.String(), nil
+}
+
+// strftime formats v with the strftime-style pattern, e.g. "%Y-%m-%d"
+func (fn *dateFuncs) strftime(pattern string, v interface{}) (string, error) {
+	t, err := fn.toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'e':
+			fmt.Fprintf(&b, "%2d", t.Day())
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&b, "%02d", (t.Hour()+11)%12+1)
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'p':
+			if t.Hour() < 12 {
+				b.WriteString(fn.names.am)
+			} else {
+				b.WriteString(fn.names.pm)
+			}
+		case 'B':
+			b.WriteString(fn.names.months[t.Month()-1])
+		case 'b':
+			b.WriteString(fn.names.shortMonths[t.Month()-1])
+		case 'A':
+			b.WriteString(fn.names.days[t.Weekday()])
+		case 'a':
+			b.WriteString(fn.names.shortDays[t.Weekday()])
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 's':
+			fmt.Fprintf(&b, "%d", t.Unix())
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unknown directive %q in %q", "%"+string(pattern[i]), pattern)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// timeAgo returns the relative time of v from now, e.g. "3 days ago"
+func (fn *dateFuncs) timeAgo(v interface{}) (string, error) {
+	t, err := fn.toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	d := fn.now().Sub(t)
+	format := fn.names.ago
+	if d < 0 {
+		d, format = -d, fn.names.later
+	}
+	switch {
+	case d < time.Minute:
+		return fn.names.now, nil
+	case d < time.Hour:
+		return format(int(d/time.Minute), "minute"), nil
+	case d < 24*time.Hour:
+		return format(int(d/time.Hour), "hour"), nil
+	case d < 30*24*time.Hour:
+		return format(int(d/(24*time.Hour)), "day"), nil
+	case d < 365*24*time.Hour:
+		return format(int(d/(30*24*time.Hour)), "month"), nil
+	default:
+		return format(int(d/(365*24*time.Hour)), "year"), nil
+	}
+}
+
+// iso8601 returns v in the ISO 8601 extended format with the offset, e.g.
+// "2019-01-09T12:34:56+09:00"
+func (fn *dateFuncs) iso8601(v interface{}) (string, error) {
+	t, err := fn.toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format("2006-01-02T15:04:05-07:00"), nil
+}
+
+// rfc3339 returns v in the RFC 3339 format, e.g. "2019-01-09T03:34:56Z"
+func (fn *dateFuncs) rfc3339(v interface{}) (string, error) {
+	t, err := fn.toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(time.RFC3339), nil
+}
@@ -0,0 +1,230 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package theme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mah0x211/mixdown/util"
+	blackfriday "gopkg.in/russross/blackfriday.v2"
+)
+
+// returns v as string, e.g. Content of TrackedFile in html/template.HTML
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case fmt.Stringer:
+		return s.String()
+	}
+
+	if rv := indirectInterface(reflect.ValueOf(v)); !rv.IsValid() {
+		return ""
+	} else if rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// ellipsis is appended to the truncated string
+const ellipsis = "…"
+
+// fnTruncate truncates v to n runes with ellipsis
+func fnTruncate(n int, v interface{}) string {
+	str := toString(v)
+	if n < 0 || utf8.RuneCountInString(str) <= n {
+		return str
+	}
+
+	i := 0
+	for pos := range str {
+		if i == n {
+			return strings.TrimRightFunc(str[:pos], unicode.IsSpace) + ellipsis
+		}
+		i++
+	}
+	return str
+}
+
+// fnTruncateWords truncates v to n words with ellipsis
+func fnTruncateWords(n int, v interface{}) string {
+	str := toString(v)
+	words := strings.Fields(str)
+	if n < 0 || len(words) <= n {
+		return str
+	}
+	return strings.Join(words[:n], " ") + ellipsis
+}
+
+var (
+	reHTMLTag    = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]*>`)
+	reWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// fnStripHTML removes HTML tags and comments from v
+func fnStripHTML(v interface{}) string {
+	return reHTMLTag.ReplaceAllString(toString(v), "")
+}
+
+// fnPlainify removes HTML tags from v, unescapes the entities and collapses
+// the whitespaces into a space
+func fnPlainify(v interface{}) string {
+	str := html.UnescapeString(fnStripHTML(v))
+	return strings.TrimSpace(reWhitespace.ReplaceAllString(str, " "))
+}
+
+// safeRenderer renders the markdown of the untrusted text. the raw HTML is
+// skipped, and the links and images to the URL of the unsafe scheme such as
+// "javascript:" are rendered as the text without the tags.
+type safeRenderer struct {
+	*blackfriday.HTMLRenderer
+}
+
+// the schemes of the URL that can be linked
+var safeSchemes = map[string]bool{
+	"":       true,
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"ftp":    true,
+}
+
+// returns true if dest is the relative URL or the URL of safeSchemes
+func isSafeURL(dest []byte) bool {
+	u, err := url.Parse(string(dest))
+	return err == nil && safeSchemes[strings.ToLower(u.Scheme)]
+}
+
+// RenderNode renders node by HTMLRenderer unless it links to the unsafe URL
+func (r *safeRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.Link:
+		if !isSafeURL(node.LinkData.Destination) {
+			return blackfriday.GoToNext
+		}
+	case blackfriday.Image:
+		if !isSafeURL(node.LinkData.Destination) {
+			return blackfriday.SkipChildren
+		}
+	}
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+// fnMarkdownify renders v as markdown into HTML, the paragraph tag of the
+// single paragraph is removed to use it in inline. the raw HTML and the links
+// to the unsafe URL in v are not rendered.
+func fnMarkdownify(v interface{}) htmltemplate.HTML {
+	r := &safeRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags: blackfriday.CommonHTMLFlags | blackfriday.SkipHTML,
+		}),
+	}
+	out := bytes.TrimSpace(blackfriday.Run([]byte(toString(v)), blackfriday.WithRenderer(r)))
+	if bytes.HasPrefix(out, []byte("<p>")) && bytes.HasSuffix(out, []byte("</p>")) &&
+		bytes.Count(out, []byte("<p>")) == 1 {
+		out = out[3 : len(out)-4]
+	}
+	return htmltemplate.HTML(out)
+}
+
+// fnReplace replaces all old in v with new
+func fnReplace(old, new string, v interface{}) string {
+	return strings.Replace(toString(v), old, new, -1)
+}
+
+// fnRegexReplace replaces all matches of pattern in v with repl, repl can
+// refer the submatches as $1
+func fnRegexReplace(pattern, repl string, v interface{}) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(toString(v), repl), nil
+}
+
+// fnTitle converts the first letter of each word in v to upper case
+func fnTitle(v interface{}) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) || unicode.IsPunct(prev) && prev != '\'' {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, toString(v))
+}
+
+// fnUrlize converts v to the lower case words joined by hyphens that can be
+// used as a segment of URL
+func fnUrlize(v interface{}) string {
+	return url.PathEscape(util.Slugify(toString(v)))
+}
+
+// fnJSONify encodes v into JSON, the characters <, > and & are escaped to
+// embed it into HTML safely. it is output as is in the script of html.
+func fnJSONify(v interface{}) (htmltemplate.JS, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return htmltemplate.JS(buf), nil
+}
+
+// xmlEscaper escapes the special characters of XML
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+)
+
+// fnXML escapes v to output it as the text or the attribute value in the xml
+// templates such as feed.mix.xml, that do not escape the values. the html
+// templates escape the values without it.
+func fnXML(v interface{}) string {
+	return xmlEscaper.Replace(toString(v))
+}
+
+// fnUpper converts v to upper case
+func fnUpper(v interface{}) string {
+	return strings.ToUpper(toString(v))
+}
+
+// fnLower converts v to lower case
+func fnLower(v interface{}) string {
+	return strings.ToLower(toString(v))
+}
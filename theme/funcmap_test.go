@@ -1 +1,691 @@
 package theme
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func newTestDateFuncs(t *testing.T, tz, lang string) *dateFuncs {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &dateFuncs{
+		loc:   loc,
+		names: lookupDateNames(lang),
+		now: func() time.Time {
+			return time.Date(2019, 1, 9, 12, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+// execute the text of the template with all template functions in UTC
+func execTestTemplate(t *testing.T, text string, data interface{}) (string, error) {
+	t.Helper()
+	tmpl, err := template.New("").Funcs(defaultFuncMap).Funcs(newDateFuncMap(time.UTC, "en")).Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+	return b.String(), err
+}
+
+func TestDateFuncsToTime(t *testing.T) {
+	fn := newTestDateFuncs(t, "Asia/Tokyo", "en")
+	want := time.Date(2019, 1, 9, 3, 4, 5, 0, time.UTC)
+
+	for _, v := range []interface{}{
+		"20190109T030405Z",
+		"1547003045",
+		int64(1547003045),
+		"2019-01-09T12:04:05+09:00",
+		"2019-01-09 12:04:05",
+		want,
+	} {
+		got, err := fn.toTime(v)
+		if err != nil {
+			t.Fatalf("toTime(%#v): %s", v, err)
+		} else if !got.Equal(want) {
+			t.Errorf("toTime(%#v) = %s, want %s", v, got, want)
+		}
+	}
+
+	for _, v := range []interface{}{"yesterday", nil, []int{}} {
+		if _, err := fn.toTime(v); err == nil {
+			t.Errorf("toTime(%#v) returns no error", v)
+		}
+	}
+}
+
+func TestDateFuncsLocalTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = loc
+
+	funcs := newDateFuncMap(nil, "en")
+	rfc3339 := funcs["rfc3339"].(func(interface{}) (string, error))
+	want := "2019-01-09T12:04:05+09:00"
+	for _, v := range []interface{}{
+		"20190109T030405Z",
+		time.Date(2019, 1, 9, 3, 4, 5, 0, time.UTC),
+	} {
+		if got, err := rfc3339(v); err != nil {
+			t.Fatal(err)
+		} else if got != want {
+			t.Errorf("rfc3339(%#v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestDateFuncsDateFormat(t *testing.T) {
+	for _, c := range []struct {
+		lang   string
+		layout string
+		want   string
+	}{
+		{"en", "Monday, January 2, 2006 3:04 PM", "Wednesday, January 9, 2019 12:04 PM"},
+		{"en", "Mon Jan 2", "Wed Jan 9"},
+		{"ja", "2006年January2日 (Mon) PM3時", "2019年1月9日 (水) 午後12時"},
+		{"ja-JP", "Monday", "水曜日"},
+	} {
+		fn := newTestDateFuncs(t, "Asia/Tokyo", c.lang)
+		got, err := fn.dateFormat(c.layout, "20190109T030405Z")
+		if err != nil {
+			t.Fatal(err)
+		} else if got != c.want {
+			t.Errorf("dateFormat(%q) in %q = %q, want %q", c.layout, c.lang, got, c.want)
+		}
+	}
+}
+
+func TestDateFuncsStrftime(t *testing.T) {
+	fn := newTestDateFuncs(t, "UTC", "en")
+	got, err := fn.strftime("%Y-%m-%d %H:%M:%S %a %b %j %% %Z", "20190109T030405Z")
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "2019-01-09 03:04:05 Wed Jan 009 % UTC"; got != want {
+		t.Errorf("strftime() = %q, want %q", got, want)
+	}
+
+	fn = newTestDateFuncs(t, "Asia/Tokyo", "ja")
+	got, err = fn.strftime("%B%e日(%A) %p%I時", "20190109T030405Z")
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "1月 9日(水曜日) 午後12時"; got != want {
+		t.Errorf("strftime() = %q, want %q", got, want)
+	}
+
+	if _, err = fn.strftime("%Q", "20190109T030405Z"); err == nil {
+		t.Error("strftime() with unknown directive returns no error")
+	}
+}
+
+func TestDateFuncsTimeAgo(t *testing.T) {
+	for _, c := range []struct {
+		lang string
+		v    string
+		want string
+	}{
+		{"en", "20190109T115930Z", "just now"},
+		{"en", "20190109T115900Z", "1 minute ago"},
+		{"en", "20190106T120000Z", "3 days ago"},
+		{"en", "20190112T120000Z", "in 3 days"},
+		{"en", "20170109T120000Z", "2 years ago"},
+		{"ja", "20190106T120000Z", "3日前"},
+		{"ja", "20190109T150000Z", "3時間後"},
+	} {
+		fn := newTestDateFuncs(t, "UTC", c.lang)
+		got, err := fn.timeAgo(c.v)
+		if err != nil {
+			t.Fatal(err)
+		} else if got != c.want {
+			t.Errorf("timeAgo(%q) in %q = %q, want %q", c.v, c.lang, got, c.want)
+		}
+	}
+}
+
+func TestDateFuncsISO8601(t *testing.T) {
+	fn := newTestDateFuncs(t, "Asia/Tokyo", "en")
+	if got, err := fn.iso8601("20190109T030405Z"); err != nil {
+		t.Fatal(err)
+	} else if want := "2019-01-09T12:04:05+09:00"; got != want {
+		t.Errorf("iso8601() = %q, want %q", got, want)
+	}
+
+	tm, err := fn.inTimezone("UTC", "20190109T030405Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fn.rfc3339(tm); err != nil {
+		t.Fatal(err)
+	} else if want := "2019-01-09T03:04:05Z"; got != want {
+		t.Errorf("rfc3339() = %q, want %q", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, c := range []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{5, "hello world", "hello…"},
+		{6, "hello world", "hello…"},
+		{20, "hello world", "hello world"},
+		{0, "hello", "…"},
+		{-1, "hello", "hello"},
+		{5, "", ""},
+		// the number of characters, not bytes
+		{3, "日本語の文章", "日本語…"},
+		{6, "日本語の文章", "日本語の文章"},
+		{2, "日　本語", "日…"},
+		{2, "🍣🍺🍵", "🍣🍺…"},
+		{4, "café au lait", "café…"},
+	} {
+		if got := fnTruncate(c.n, c.s); got != c.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", c.n, c.s, got, c.want)
+		}
+	}
+}
+
+func TestTruncateWords(t *testing.T) {
+	for _, c := range []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{2, "the quick  brown fox", "the quick…"},
+		{4, "the quick brown fox", "the quick brown fox"},
+		{0, "the quick", "…"},
+	} {
+		if got := fnTruncateWords(c.n, c.s); got != c.want {
+			t.Errorf("truncateWords(%d, %q) = %q, want %q", c.n, c.s, got, c.want)
+		}
+	}
+}
+
+func TestStripHTMLAndPlainify(t *testing.T) {
+	s := "<p>Hello <!-- note -->\n  <a href=\"/\">World</a> &amp; you</p>"
+	if got, want := fnStripHTML(s), "Hello \n  World &amp; you"; got != want {
+		t.Errorf("stripHTML() = %q, want %q", got, want)
+	}
+	if got, want := fnPlainify(s), "Hello World & you"; got != want {
+		t.Errorf("plainify() = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownify(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want string
+	}{
+		{"*hello* `world`", "<em>hello</em> <code>world</code>"},
+		{"first\n\nsecond", "<p>first</p>\n\n<p>second</p>"},
+		{"", ""},
+		// untrusted HTML and URL
+		{`<script>alert(1)</script>`, "alert(1)"},
+		{`a <img src=x onerror=alert(1)> b`, "a  b"},
+		{"[x](javascript:alert) [y](JavaScript:alert)", "x y"},
+		{"![x](javascript:alert) [y](data:text/html,z)", " y"},
+		{"[x](/about.html) [y](https://example.com/?a=1&b=2)", `<a href="/about.html">x</a> <a href="https://example.com/?a=1&amp;b=2">y</a>`},
+	} {
+		if got := string(fnMarkdownify(c.s)); got != c.want {
+			t.Errorf("markdownify(%q) = %q, want %q", c.s, got, c.want)
+		}
+	}
+}
+
+func TestXML(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		want string
+	}{
+		{"Hello <World>", "Hello &lt;World&gt;"},
+		{`Tom & "Jerry's"`, "Tom &amp; &#34;Jerry&#39;s&#34;"},
+		{htmltemplate.HTML("<p>body</p>"), "&lt;p&gt;body&lt;/p&gt;"},
+		{nil, ""},
+	} {
+		if got := fnXML(c.v); got != c.want {
+			t.Errorf("xml(%#v) = %q, want %q", c.v, got, c.want)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	if got, want := fnReplace("a", "o", "banana"), "bonono"; got != want {
+		t.Errorf("replace() = %q, want %q", got, want)
+	}
+
+	got, err := fnRegexReplace(`(\d+)-(\d+)`, "$2-$1", "12-34 56-78")
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "34-12 78-56"; got != want {
+		t.Errorf("regexReplace() = %q, want %q", got, want)
+	}
+	if _, err = fnRegexReplace(`(`, "", "x"); err == nil {
+		t.Error("regexReplace() with invalid pattern returns no error")
+	}
+}
+
+func TestTitle(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want string
+	}{
+		{"hello world", "Hello World"},
+		{"don't stop-me now", "Don't Stop-Me Now"},
+		{"ÉCOLE élève", "ÉCOLE Élève"},
+	} {
+		if got := fnTitle(c.s); got != c.want {
+			t.Errorf("title(%q) = %q, want %q", c.s, got, c.want)
+		}
+	}
+}
+
+func TestUrlize(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Go 1.16 -- release  ", "go-1-16-release"},
+		{"日本語 タイトル", "%E6%97%A5%E6%9C%AC%E8%AA%9E-%E3%82%BF%E3%82%A4%E3%83%88%E3%83%AB"},
+	} {
+		if got := fnUrlize(c.s); got != c.want {
+			t.Errorf("urlize(%q) = %q, want %q", c.s, got, c.want)
+		}
+	}
+}
+
+func TestJSONify(t *testing.T) {
+	got, err := fnJSONify(map[string]interface{}{
+		"html": "</script><b>&</b>",
+		"list": []int{1, 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if want := `{"html":"\u003c/script\u003e\u003cb\u003e\u0026\u003c/b\u003e","list":[1,2]}`; string(got) != want {
+		t.Errorf("jsonify() = %s, want %s", got, want)
+	}
+
+	if _, err = fnJSONify(func() {}); err == nil {
+		t.Error("jsonify() with func returns no error")
+	}
+}
+
+type testPost struct {
+	Subject  string
+	Cdate    string
+	Hashtags []string
+	Score    int
+}
+
+func newTestPosts() []*testPost {
+	return []*testPost{
+		{"a", "20181230T000000Z", []string{"#release"}, 3},
+		{"b", "20190105T000000Z", []string{"#note"}, 1},
+		{"c", "20190109T000000Z", []string{"#release", "#note"}, 2},
+		{"d", "20190201T000000Z", nil, 2},
+	}
+}
+
+func subjects(t *testing.T, v interface{}) string {
+	var list []string
+	switch posts := v.(type) {
+	case reflect.Value:
+		return subjects(t, posts.Interface())
+	case []*testPost:
+		for _, p := range posts {
+			list = append(list, p.Subject)
+		}
+	default:
+		t.Fatalf("unexpected type %T", v)
+	}
+	return strings.Join(list, ",")
+}
+
+func TestWhere(t *testing.T) {
+	posts := newTestPosts()
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+		want string
+	}{
+		{"Subject", []interface{}{"b"}, "b"},
+		{"Subject", []interface{}{"x"}, ""},
+		{"Hashtags", []interface{}{"contains", "#release"}, "a,c"},
+		{"Hashtags", []interface{}{"not contains", "#release"}, "b,d"},
+		{"Score", []interface{}{">=", 2}, "a,c,d"},
+		{"Score", []interface{}{"!=", 2.0}, "a,b"},
+		{"Cdate", []interface{}{"<", "20190101T000000Z"}, "a"},
+		{"Subject", []interface{}{"in", []string{"a", "d"}}, "a,d"},
+		{"Subject", []interface{}{"not in", []string{"a", "d"}}, "b,c"},
+	} {
+		got, err := fnWhere(c.key, append(c.args, posts)...)
+		if err != nil {
+			t.Fatalf("where(%q, %v): %s", c.key, c.args, err)
+		} else if s := subjects(t, got); s != c.want {
+			t.Errorf("where(%q, %v) = %q, want %q", c.key, c.args, s, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+	}{
+		{"Unknown", []interface{}{"x", posts}},
+		{"Score", []interface{}{"~", 1, posts}},
+		{"Score", []interface{}{1, 1, posts}},
+		{"Score", []interface{}{"<", "x", posts}},
+		{"Score", []interface{}{"contains", 1, posts}},
+		{"Score", []interface{}{posts}},
+		{"Score", []interface{}{1, nil}},
+		{"Score", []interface{}{1, "abc"}},
+	} {
+		if _, err := fnWhere(c.key, c.args...); err == nil {
+			t.Errorf("where(%q, %v) returns no error", c.key, c.args)
+		}
+	}
+
+	// map items
+	got, err := fnWhere("name", "x", []map[string]string{{"name": "x"}, {"name": "y"}, {}})
+	if err != nil {
+		t.Fatal(err)
+	} else if list := got.Interface().([]map[string]string); len(list) != 1 || list[0]["name"] != "x" {
+		t.Errorf("where() = %v", list)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	posts := newTestPosts()
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+		want string
+	}{
+		{"Score", nil, "b,c,d,a"},
+		{"Score", []interface{}{"asc"}, "b,c,d,a"},
+		{"Score", []interface{}{"desc"}, "a,c,d,b"},
+		{"Cdate", []interface{}{"desc"}, "d,c,b,a"},
+		{"Subject", []interface{}{"desc"}, "d,c,b,a"},
+	} {
+		got, err := fnSortBy(c.key, append(c.args, posts)...)
+		if err != nil {
+			t.Fatal(err)
+		} else if s := subjects(t, got); s != c.want {
+			t.Errorf("sortBy(%q, %v) = %q, want %q", c.key, c.args, s, c.want)
+		}
+	}
+	if s := subjects(t, posts); s != "a,b,c,d" {
+		t.Errorf("sortBy() modified the list: %q", s)
+	}
+
+	for _, c := range []struct {
+		key  string
+		list interface{}
+		want interface{}
+	}{
+		{"", []interface{}{3, 1.5, 2}, []interface{}{1.5, 2, 3}},
+		{"", [3]string{"b", "c", "a"}, []string{"a", "b", "c"}},
+		{"", []string{}, []string{}},
+		// the items without the key come first
+		{
+			"n",
+			[]map[string]int{{"n": 2}, {}, {"n": 1}},
+			[]map[string]int{{}, {"n": 1}, {"n": 2}},
+		},
+	} {
+		got, err := fnSortBy(c.key, c.list)
+		if err != nil {
+			t.Fatalf("sortBy(%q, %v): %s", c.key, c.list, err)
+		} else if !reflect.DeepEqual(got.Interface(), c.want) {
+			t.Errorf("sortBy(%q, %v) = %v, want %v", c.key, c.list, got, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+	}{
+		{"Unknown", []interface{}{posts}},
+		{"Score", []interface{}{"up", posts}},
+		{"Score", []interface{}{"asc", "desc", posts}},
+		{"Score", nil},
+		{"Score", []interface{}{nil}},
+		{"", []interface{}{[]interface{}{1, "a"}}},
+		{"", []interface{}{[]interface{}{[]int{1}, []int{2}}}},
+	} {
+		if _, err := fnSortBy(c.key, c.args...); err == nil {
+			t.Errorf("sortBy(%q, %v) returns no error", c.key, c.args)
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	fn := newTestDateFuncs(t, "Asia/Tokyo", "en")
+	posts := newTestPosts()
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+		want string
+	}{
+		{"Score", nil, "3:a 1:b 2:c,d"},
+		{"Cdate", []interface{}{"year"}, "2018:a 2019:b,c,d"},
+		{"Cdate", []interface{}{"month"}, "2018-12:a 2019-01:b,c 2019-02:d"},
+		{"Cdate", []interface{}{"day"}, "2018-12-30:a 2019-01-05:b 2019-01-09:c 2019-02-01:d"},
+		{"Cdate", []interface{}{"Jan 2006"}, "Dec 2018:a Jan 2019:b,c Feb 2019:d"},
+		{"Hashtags", nil, "#release:a,c #note:b,c"},
+	} {
+		groups, err := fn.groupBy(c.key, append(c.args, posts)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list []string
+		for _, g := range groups {
+			list = append(list, fmt.Sprintf("%v:%s", g.Key, subjects(t, g.Items)))
+		}
+		if s := strings.Join(list, " "); s != c.want {
+			t.Errorf("groupBy(%q, %v) = %q, want %q", c.key, c.args, s, c.want)
+		}
+	}
+
+	// the items without the date are not in any group
+	type item struct {
+		Date time.Time
+	}
+	for _, list := range []interface{}{
+		[]item{{}, {time.Date(2019, 1, 9, 0, 0, 0, 0, time.UTC)}, {}},
+		[]map[string]string{{}, {"Date": "20190109T000000Z"}, {"Date": ""}},
+	} {
+		groups, err := fn.groupBy("Date", "year", list)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(groups) != 1 || groups[0].Key != "2019" || reflect.ValueOf(groups[0].Items).Len() != 1 {
+			t.Errorf("groupBy() of %v = %v", list, groups)
+		}
+	}
+
+	// the items without the key are in the group of nil
+	groups, err := fn.groupBy("n", []map[string]int{{"n": 1}, {}, {"n": 1}})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(groups) != 2 || groups[0].Key != 1 || groups[1].Key != nil {
+		t.Errorf("groupBy() = %v", groups)
+	}
+
+	for _, c := range []struct {
+		key  string
+		args []interface{}
+	}{
+		{"Unknown", []interface{}{posts}},
+		{"Subject", []interface{}{"year", posts}},
+		{"Cdate", []interface{}{1, posts}},
+		{"Cdate", []interface{}{"year", "x", posts}},
+		{"Cdate", nil},
+		{"Cdate", []interface{}{"year", 1}},
+		{"", []interface{}{[]map[string]int{{}}}},
+	} {
+		if _, err := fn.groupBy(c.key, c.args...); err == nil {
+			t.Errorf("groupBy(%q, %v) returns no error", c.key, c.args)
+		}
+	}
+}
+
+func TestFirstLastAfter(t *testing.T) {
+	posts := newTestPosts()
+	for _, c := range []struct {
+		name string
+		fn   func(int, reflect.Value) (reflect.Value, error)
+		n    int
+		want string
+	}{
+		{"first", fnFirst, 2, "a,b"},
+		{"first", fnFirst, 0, ""},
+		{"first", fnFirst, 10, "a,b,c,d"},
+		{"last", fnLast, 1, "d"},
+		{"last", fnLast, 0, ""},
+		{"last", fnLast, 10, "a,b,c,d"},
+		{"after", fnAfter, 3, "d"},
+		{"after", fnAfter, 0, "a,b,c,d"},
+		{"after", fnAfter, 10, ""},
+	} {
+		got, err := c.fn(c.n, reflect.ValueOf(posts))
+		if err != nil {
+			t.Fatal(err)
+		} else if s := subjects(t, got); s != c.want {
+			t.Errorf("%s(%d) = %q, want %q", c.name, c.n, s, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		fn   func(int, reflect.Value) (reflect.Value, error)
+		n    int
+		list interface{}
+		want interface{}
+	}{
+		{"first", fnFirst, 2, [3]int{1, 2, 3}, []int{1, 2}},
+		{"last", fnLast, 2, [3]int{1, 2, 3}, []int{2, 3}},
+		{"after", fnAfter, 2, [3]int{1, 2, 3}, []int{3}},
+		{"first", fnFirst, 1, []int{}, []int{}},
+		{"last", fnLast, 1, []int{}, []int{}},
+		{"after", fnAfter, 1, []int{}, []int{}},
+	} {
+		got, err := c.fn(c.n, reflect.ValueOf(c.list))
+		if err != nil {
+			t.Fatalf("%s(%d, %v): %s", c.name, c.n, c.list, err)
+		} else if !reflect.DeepEqual(got.Interface(), c.want) {
+			t.Errorf("%s(%d, %v) = %v, want %v", c.name, c.n, c.list, got, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		fn   func(int, reflect.Value) (reflect.Value, error)
+		n    int
+		list interface{}
+	}{
+		{"first", fnFirst, -1, posts},
+		{"last", fnLast, -1, posts},
+		{"after", fnAfter, -1, posts},
+		{"first", fnFirst, 1, 1},
+		{"last", fnLast, 1, "abc"},
+		{"after", fnAfter, 1, nil},
+	} {
+		if _, err := c.fn(c.n, reflect.ValueOf(c.list)); err == nil {
+			t.Errorf("%s(%d, %#v) returns no error", c.name, c.n, c.list)
+		}
+	}
+}
+
+func TestUniqUnionIntersect(t *testing.T) {
+	posts := newTestPosts()
+	got, err := fnUniq(reflect.ValueOf(append(posts, posts[1], posts[0])))
+	if err != nil {
+		t.Fatal(err)
+	} else if s := subjects(t, got); s != "a,b,c,d" {
+		t.Errorf("uniq() = %q", s)
+	}
+
+	a := reflect.ValueOf([]string{"x", "y", "y"})
+	b := reflect.ValueOf([]string{"z", "y"})
+	if got, err = fnUnion(a, b); err != nil {
+		t.Fatal(err)
+	} else if want := []string{"x", "y", "z"}; !reflect.DeepEqual(got.Interface(), want) {
+		t.Errorf("union() = %v, want %v", got, want)
+	}
+	if got, err = fnIntersect(a, b); err != nil {
+		t.Fatal(err)
+	} else if want := []string{"y"}; !reflect.DeepEqual(got.Interface(), want) {
+		t.Errorf("intersect() = %v, want %v", got, want)
+	}
+	if got, err = fnUnion(a, reflect.ValueOf([]int{1})); err != nil {
+		t.Fatal(err)
+	} else if want := []interface{}{"x", "y", 1}; !reflect.DeepEqual(got.Interface(), want) {
+		t.Errorf("union() = %v, want %v", got, want)
+	}
+}
+
+func TestDictList(t *testing.T) {
+	m, err := fnDict("a", 1, "b", "x")
+	if err != nil {
+		t.Fatal(err)
+	} else if want := map[string]interface{}{"a": 1, "b": "x"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("dict() = %v, want %v", m, want)
+	}
+	if _, err = fnDict("a"); err == nil {
+		t.Error("dict() with odd arguments returns no error")
+	}
+	if _, err = fnDict(1, 2); err == nil {
+		t.Error("dict() with non-string key returns no error")
+	}
+
+	if got := fnList(); got == nil || len(got) != 0 {
+		t.Errorf("list() = %#v", got)
+	}
+}
+
+func TestFuncsInTemplate(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		want string
+	}{
+		{`{{range where "Hashtags" "contains" "#release" . | sortBy "Cdate" "desc" | first 5}}{{.Subject}}{{end}}`, "ca"},
+		{`{{range groupBy "Cdate" "year" .}}{{.Key}}={{len .Items}};{{end}}`, "2018=1;2019=3;"},
+		{`{{range after 1 . | last 2}}{{.Subject}}{{end}}`, "cd"},
+		{`{{with dict "n" (list 1 2)}}{{index .n 1}}{{end}}`, "2"},
+		{`{{range . | first 1}}{{.Cdate | dateFormat "2006-01-02"}} {{.Subject | upper | truncate 3}}{{end}}`, "2018-12-30 A"},
+		{`{{"<b>x</b> y" | plainify | title}}`, "X Y"},
+	} {
+		if got, err := execTestTemplate(t, c.text, newTestPosts()); err != nil {
+			t.Errorf("%s: %s", c.text, err)
+		} else if got != c.want {
+			t.Errorf("%s = %q, want %q", c.text, got, c.want)
+		}
+	}
+
+	// the errors of the functions stop the execution
+	for _, text := range []string{
+		`{{first -1 .}}`,
+		`{{last 1 "abc"}}`,
+		`{{sortBy "Unknown" .}}`,
+		`{{where "Score" "~" 1 .}}`,
+		`{{groupBy "Subject" "year" .}}`,
+		`{{dict "a"}}`,
+		`{{"x" | dateFormat "2006"}}`,
+		`{{regexReplace "(" "" "x"}}`,
+	} {
+		if _, err := execTestTemplate(t, text, newTestPosts()); err == nil {
+			t.Errorf("%s returns no error", text)
+		}
+	}
+}
@@ -26,41 +26,188 @@ package theme
 
 import (
 	"fmt"
+	htmltemplate "html/template"
 	"io"
-	"io/ioutil"
-	"log"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/mah0x211/mixdown/logger"
 	"github.com/mah0x211/mixdown/rex"
 	"github.com/mah0x211/mixdown/util"
 )
 
 // Theme is the representation of the manager of theme files and assets
 type Theme struct {
-	name   string
-	assets map[string]string
-	tmpls  map[string]*template.Template
+	name string
+	dirs []*themeDir
+	// theme directories that contain the asset directory of the name in
+	// order of precedence
+	assets map[string][]*themeDir
+	// templates by name and the extension of the output
+	tmpls map[string]map[string]themeTemplate
 }
 
-func parseTemplate(tmpl *template.Template, src string) error {
-	buf, err := ioutil.ReadFile(src)
-	if err != nil {
+// themeTemplate is the template of text/template or html/template
+type themeTemplate interface {
+	Funcs(funcMap template.FuncMap)
+	// Parse parses text as the associated template of name
+	Parse(name, text string) error
+	// Alias associates the body of the template of name with alias if it is
+	// not empty
+	Alias(alias, name string) error
+	Execute(wr io.Writer, data interface{}) error
+}
+
+type textTemplate struct {
+	*template.Template
+}
+
+func (t textTemplate) Funcs(funcMap template.FuncMap) {
+	t.Template.Funcs(funcMap)
+}
+
+func (t textTemplate) Parse(name, text string) error {
+	_, err := t.Template.New(name).Parse(text)
+	return err
+}
+
+func (t textTemplate) Alias(alias, name string) error {
+	if tmpl := t.Template.Lookup(name); tmpl != nil && tmpl.Tree != nil {
+		_, err := t.Template.AddParseTree(alias, tmpl.Tree)
 		return err
-	} else if _, err = tmpl.Parse(string(buf)); err != nil {
+	}
+	return nil
+}
+
+// htmlTemplate escapes the values contextually, the values of
+// html/template.HTML such as Content of TrackedFile are output as is.
+type htmlTemplate struct {
+	*htmltemplate.Template
+}
+
+func (t htmlTemplate) Funcs(funcMap template.FuncMap) {
+	t.Template.Funcs(htmltemplate.FuncMap(funcMap))
+}
+
+func (t htmlTemplate) Parse(name, text string) error {
+	_, err := t.Template.New(name).Parse(text)
+	return err
+}
+
+func (t htmlTemplate) Alias(alias, name string) error {
+	if tmpl := t.Template.Lookup(name); tmpl != nil && tmpl.Tree != nil {
+		_, err := t.Template.AddParseTree(alias, tmpl.Tree)
 		return err
 	}
+	return nil
+}
+
+// Execute executes the template looked up by name, since AddParseTree
+// replaces the associated template of the same name.
+func (t htmlTemplate) Execute(wr io.Writer, data interface{}) error {
+	return t.Template.ExecuteTemplate(wr, t.Name(), data)
+}
+
+// IsHTMLExt returns true if ext is the extension of the html output, the
+// template of the html output escapes the values contextually
+func IsHTMLExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case "html", "htm", "xhtml":
+		return true
+	}
+	return false
+}
+
+// create the template of html/template for the html output, or text/template
+// for the others such as xml or text
+func newTemplate(name, ext string) themeTemplate {
+	if IsHTMLExt(ext) {
+		return htmlTemplate{htmltemplate.New(name)}
+	}
+	return textTemplate{template.New(name)}
+}
+
+// parser parses the theme files into the template. every file is parsed as
+// the template named by its pathname, so that the errors of parse and
+// execution are reported with the pathname and the line number.
+type parser struct {
+	tmpl themeTemplate
+	// theme directories in order of precedence
+	dirs   []*themeDir
+	parsed map[string]bool
+	// pathnames of the files that are including the current file
+	stack []string
+}
+
+func newParser(tmpl themeTemplate, dirs []*themeDir) *parser {
+	return &parser{
+		tmpl:   tmpl,
+		dirs:   dirs,
+		parsed: make(map[string]bool),
+	}
+}
+
+// returns the first theme directory that contains the file of name
+func (p *parser) lookup(name string) *themeDir {
+	for _, dir := range p.dirs {
+		if info, err := fs.Stat(dir.fsys, name); err == nil && info.Mode().IsRegular() {
+			return dir
+		}
+	}
+	return p.dirs[0]
+}
+
+// returns err with the chain of the including files
+func includedFrom(err error, chain []string) error {
+	if len(chain) > 0 {
+		return fmt.Errorf("%s (included from %s)", err, strings.Join(chain, " -> "))
+	}
+	return err
+}
+
+// parse the file of name in dir as the body of the template of alias, and the
+// files included by {{template "@<file>" .}} actions as the templates of
+// "@<file>". the file that has already been parsed is skipped.
+func (p *parser) parse(alias string, dir *themeDir, name string) error {
+	src := dir.pathname(name)
+	for i, pathname := range p.stack {
+		if pathname == src {
+			cycle := append(append([]string{}, p.stack[i:]...), src)
+			err := fmt.Errorf("include cycle %s", strings.Join(cycle, " -> "))
+			return includedFrom(err, p.stack[:i])
+		}
+	}
+	if p.parsed[src] {
+		return p.tmpl.Alias(alias, src)
+	}
+	p.parsed[src] = true
+
+	buf, err := fs.ReadFile(dir.fsys, name)
+	if err != nil {
+		return includedFrom(err, p.stack)
+	} else if err = p.tmpl.Parse(src, string(buf)); err != nil {
+		return includedFrom(err, p.stack)
+	} else if err = p.tmpl.Alias(alias, src); err != nil {
+		return includedFrom(err, p.stack)
+	}
+
+	p.stack = append(p.stack, src)
+	defer func() {
+		p.stack = p.stack[:len(p.stack)-1]
+	}()
 
 	// lookup nested-template actions
-	dirname := filepath.Dir(src)
 	matches := rex.TemplateAction.FindAllSubmatch(buf, -1)
 	for _, match := range matches {
 		// append nested-template
 		if match[1] != nil {
-			src = filepath.Join(dirname, string(match[1]))
-			err = parseTemplate(tmpl, src)
-			if err != nil {
+			name := string(match[1])
+			if err = p.parse("@"+name, p.lookup(name), name); err != nil {
 				return err
 			}
 		}
@@ -69,91 +216,243 @@ func parseTemplate(tmpl *template.Template, src string) error {
 	return nil
 }
 
-// New allocate a instance of Theme
+// New allocate a instance of Theme. the templates, partials and asset files
+// that are missing in themedir are looked up in its parent themes. the
+// default theme embedded in the binary is used if themedir does not exist.
 func New(themedir string) (*Theme, error) {
-	tmpls := make(map[string]*template.Template)
-	assets := make(map[string]string)
+	tmpls := make(map[string]map[string]themeTemplate)
+	assets := make(map[string][]*themeDir)
 
-	// verify theme directory
-	if ok, err := util.IsDir(themedir); err != nil {
-		return nil, err
-	} else if !ok {
-		return nil, fmt.Errorf("theme %q is not found", themedir)
-	}
-
-	// load theme-files
-	finfos, err := ioutil.ReadDir(themedir)
+	// verify theme directory and its parents
+	dirs, err := lookupDirs(themedir)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, k := 0, len(finfos); i < k; i++ {
-		fname := finfos[i].Name()
-
-		if strings.HasPrefix(fname, ".") {
-			// ignore dot-file
-			continue
-		} else if finfos[i].IsDir() {
-			// holds asset dirinfo
-			assets[fname] = filepath.Join(themedir, fname)
-			log.Printf("asset %q", fname)
-			continue
+	for _, dir := range dirs {
+		// load theme-files
+		entries, err := fs.ReadDir(dir.fsys, ".")
+		if err != nil {
+			return nil, err
 		}
 
-		// ignore non-template-file
-		if !rex.ThemeFile.MatchString(fname) {
-			continue
-		}
+		for _, entry := range entries {
+			fname := entry.Name()
 
-		// decompose filename
-		matches := rex.ThemeFile.FindStringSubmatch(fname)
-		basename, layout := matches[1], matches[2]
-		log.Printf("template %q - %q", basename, fname)
+			if strings.HasPrefix(fname, ".") {
+				// ignore dot-file
+				continue
+			} else if entry.IsDir() {
+				// holds asset dirinfo
+				assets[fname] = append(assets[fname], dir)
+				logger.Debugf("asset %q - %q", fname, dir.name)
+				continue
+			}
 
-		// parse template file
-		src := filepath.Join(themedir, fname)
-		tmpl := template.New(basename)
-		tmpl.Funcs(defaultFuncMap)
-		if err = parseTemplate(tmpl, src); err != nil {
-			return nil, err
-		}
+			// ignore non-template-file
+			if !rex.ThemeFile.MatchString(fname) {
+				continue
+			}
 
-		// decompose filename
-		if layout != "" {
-			err = parseTemplate(tmpl, filepath.Join(themedir, layout))
-			if err != nil {
+			// decompose filename
+			matches := rex.ThemeFile.FindStringSubmatch(fname)
+			basename, ext, layout := matches[1], matches[2], matches[3]
+			if _, ok := tmpls[basename][ext]; ok {
+				// overridden by the child theme
+				logger.Debugf("template %q - %q is overridden", basename, dir.pathname(fname))
+				continue
+			}
+			logger.Debugf("template %q - %q", basename, dir.pathname(fname))
+
+			// parse template file
+			tmpl := newTemplate(basename, ext)
+			tmpl.Funcs(defaultFuncMap)
+			tmpl.Funcs(newDateFuncMap(time.Local, "en"))
+			p := newParser(tmpl, dirs)
+			if err = p.parse(basename, dir, fname); err != nil {
 				return nil, err
 			}
+
+			// decompose filename
+			if layout != "" {
+				if err = p.parse(basename, p.lookup(layout), layout); err != nil {
+					return nil, err
+				}
+			}
+			if tmpls[basename] == nil {
+				tmpls[basename] = make(map[string]themeTemplate)
+			}
+			tmpls[basename][ext] = tmpl
 		}
-		tmpls[basename] = tmpl
 	}
 
 	return &Theme{
+		dirs:   dirs,
 		assets: assets,
 		tmpls:  tmpls,
 	}, nil
 }
 
+// Dirs returns the theme directory and the directories of its parent themes
+// in order of precedence. the default theme embedded in the binary is not
+// included.
+func (t *Theme) Dirs() []string {
+	list := make([]string, 0, len(t.dirs))
+	for _, dir := range t.dirs {
+		if !dir.embedded {
+			list = append(list, dir.name)
+		}
+	}
+	return list
+}
+
+// SetLocale sets the location and the language of the date functions, e.g.
+// dateFormat converts the time to loc and localizes the names of months and
+// days with lang
+func (t *Theme) SetLocale(loc *time.Location, lang string) {
+	funcs := newDateFuncMap(loc, lang)
+	for _, exts := range t.tmpls {
+		for _, tmpl := range exts {
+			tmpl.Funcs(funcs)
+		}
+	}
+}
+
 // Exists returns true if the specified named template exists
 func (t *Theme) Exists(name string) bool {
-	return t.tmpls[name] != nil
+	return len(t.tmpls[name]) > 0
+}
+
+// Exts returns the sorted extensions of the outputs of the named template,
+// e.g. "html" and "xml" of home.mix.html and home.mix.xml
+func (t *Theme) Exts(name string) []string {
+	exts := make([]string, 0, len(t.tmpls[name]))
+	for ext := range t.tmpls[name] {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
 }
 
-// Execute applies a parsed template to the specified data object,
-// and writes the output to wr.
-func (t *Theme) Execute(wr io.Writer, name string, data interface{}) error {
-	if tmpl, ok := t.tmpls[name]; ok {
+// Names returns the sorted names of the templates
+func (t *Theme) Names() []string {
+	names := make([]string, 0, len(t.tmpls))
+	for name := range t.tmpls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Execute applies a parsed template of the output extension to the specified
+// data object, and writes the output to wr.
+func (t *Theme) Execute(wr io.Writer, name, ext string, data interface{}) error {
+	if tmpl, ok := t.tmpls[name][ext]; ok {
 		return tmpl.Execute(wr, data)
 	}
-	return fmt.Errorf("template %q not found", name)
+	return fmt.Errorf("template %q not found", name+".mix."+ext)
+}
+
+// AssetFile is the representation of a file in the asset directories
+type AssetFile struct {
+	// pathname relative to the output directory
+	Pathname string
+	Source   string
+	fsys     fs.FS
+	name     string
+}
+
+// ReadFile returns the contents of the file
+func (f *AssetFile) ReadFile() ([]byte, error) {
+	return fs.ReadFile(f.fsys, f.name)
+}
+
+// list the slash-separated pathnames of files under dirname in fsys. the
+// dotfiles are ignored.
+func listFiles(fsys fs.FS, dirname string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// follow the symlinks
+		pathname := path.Join(dirname, entry.Name())
+		if info, err := fs.Stat(fsys, pathname); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			list = append(list, pathname)
+			continue
+		}
+
+		files, err := listFiles(fsys, pathname)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, files...)
+	}
+
+	return list, nil
+}
+
+// AssetFiles returns the files in the asset directories sorted by pathname
+func (t *Theme) AssetFiles() ([]AssetFile, error) {
+	list := make([]AssetFile, 0)
+	for name, dirs := range t.assets {
+		exists := make(map[string]bool)
+		for _, dir := range dirs {
+			files, err := listFiles(dir.fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				// the file of the child theme takes precedence
+				if exists[f] {
+					continue
+				}
+				exists[f] = true
+				list = append(list, AssetFile{
+					Pathname: filepath.FromSlash(f),
+					Source:   dir.pathname(f),
+					fsys:     dir.fsys,
+					name:     f,
+				})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Pathname < list[j].Pathname
+	})
+
+	return list, nil
 }
 
-// ExportAssets copy asset directories into outdir
+// ExportAssets copy the files in the asset directories into outdir
 func (t *Theme) ExportAssets(outdir string) error {
-	for name, srcdir := range t.assets {
-		dstdir := filepath.Join(outdir, name)
-		log.Printf("export %q %q -> %q", name, srcdir, dstdir)
-		if err := util.CopyDir(srcdir, dstdir); err != nil {
+	files, err := t.AssetFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		dst := filepath.Join(outdir, f.Pathname)
+		logger.Debugf("export %q -> %q", f.Source, dst)
+		buf, err := f.ReadFile()
+		if err != nil {
+			return err
+		}
+		ofile, err := util.CreateFile(dst)
+		if err != nil {
+			return err
+		} else if _, err = ofile.Write(buf); err != nil {
+			ofile.Close()
+			return err
+		} else if err = ofile.Close(); err != nil {
 			return err
 		}
 	}
@@ -1 +1,298 @@
 package theme
+
+import (
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestTheme(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewEscaping(t *testing.T) {
+	dir := writeTestTheme(t, map[string]string{
+		"article.mix.html@layout.html": `{{define "content"}}<h1>{{.Subject}}</h1>{{.Content}}{{end}}`,
+		"layout.html":                  `<title>{{.Subject}}</title>{{template "content" .}}<a href="{{.URL}}">x</a>`,
+		"feed.mix.xml":                 `<title>{{.Subject}}</title>{{.Content}}`,
+	})
+	th, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{
+		"Subject": `<script>"x"</script>`,
+		"Content": htmltemplate.HTML("<p>body</p>"),
+		"URL":     "javascript:alert(1)",
+	}
+	for _, c := range []struct {
+		name string
+		ext  string
+		want string
+	}{
+		{
+			"article", "html",
+			`<title>&lt;script&gt;&#34;x&#34;&lt;/script&gt;</title>` +
+				`<h1>&lt;script&gt;&#34;x&#34;&lt;/script&gt;</h1><p>body</p><a href="#ZgotmplZ">x</a>`,
+		},
+		{"feed", "xml", `<title><script>"x"</script></title><p>body</p>`},
+	} {
+		var b strings.Builder
+		if err = th.Execute(&b, c.name, c.ext, data); err != nil {
+			t.Fatal(err)
+		} else if b.String() != c.want {
+			t.Errorf("%s = %q, want %q", c.name, b.String(), c.want)
+		}
+	}
+}
+
+func TestNewIncludes(t *testing.T) {
+	dir := writeTestTheme(t, map[string]string{
+		"home.mix.html@layout.html": `{{define "content"}}{{template "@item.html" .}}{{template "@item.html" .}}{{end}}`,
+		"layout.html":               `<main>{{template "content" .}}</main>{{template "@footer.html" .}}`,
+		"item.html":                 `<p>{{.}}</p>`,
+		"footer.html":               `{{define "@footer.html"}}<footer>{{template "@item.html" .}}</footer>{{end}}`,
+	})
+	th, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err = th.Execute(&b, "home", "html", "x"); err != nil {
+		t.Fatal(err)
+	} else if want := "<main><p>x</p><p>x</p></main><footer><p>x</p></footer>"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestNewIncludeCycle(t *testing.T) {
+	dir := writeTestTheme(t, map[string]string{
+		"home.mix.html": `{{template "@a.html" .}}`,
+		"a.html":        `{{template "@b.html" .}}`,
+		"b.html":        `{{template "@a.html" .}}`,
+	})
+	_, err := New(dir)
+	if err == nil {
+		t.Fatal("New() with include cycle returns no error")
+	}
+	home, a, b := filepath.Join(dir, "home.mix.html"), filepath.Join(dir, "a.html"), filepath.Join(dir, "b.html")
+	want := "include cycle " + a + " -> " + b + " -> " + a + " (included from " + home + ")"
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestNewErrorContext(t *testing.T) {
+	dir := writeTestTheme(t, map[string]string{
+		"home.mix.html": "\n{{template \"@a.html\" .}}",
+		"a.html":        "ok\n{{if}}",
+	})
+	_, err := New(dir)
+	if err == nil {
+		t.Fatal("New() with invalid template returns no error")
+	}
+	home, a := filepath.Join(dir, "home.mix.html"), filepath.Join(dir, "a.html")
+	if !strings.Contains(err.Error(), a+":2:") || !strings.HasSuffix(err.Error(), "(included from "+home+")") {
+		t.Errorf("error without context: %s", err)
+	}
+
+	dir = writeTestTheme(t, map[string]string{
+		"home.mix.txt@layout.txt": "{{define \"content\"}}\n\n{{.Foo.Bar}}{{end}}",
+		"layout.txt":              `{{template "content" .}}`,
+	})
+	th, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = th.Execute(ioutil.Discard, "home", "txt", map[string]interface{}{"Foo": 1})
+	if err == nil {
+		t.Fatal("Execute() returns no error")
+	} else if pathname := filepath.Join(dir, "home.mix.txt@layout.txt"); !strings.Contains(err.Error(), pathname+":3:") {
+		t.Errorf("error without context: %s", err)
+	}
+}
+
+func TestNewParent(t *testing.T) {
+	base := writeTestTheme(t, map[string]string{
+		"home.mix.html@layout.html": `{{define "content"}}{{template "@item.html" .}}{{end}}`,
+		"tag.mix.html@layout.html":  `{{define "content"}}tag{{end}}`,
+		"layout.html":               `<base>{{template "content" .}}</base>`,
+		"item.html":                 `<p>{{.}}</p>`,
+	})
+	if err := os.MkdirAll(filepath.Join(base, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range map[string]string{"style.css": "base", "base.js": "base"} {
+		if err := ioutil.WriteFile(filepath.Join(base, "assets", name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := writeTestTheme(t, map[string]string{
+		"theme.yaml":               "parent: " + base + "\n",
+		"tag.mix.html@layout.html": `{{define "content"}}child tag{{end}}`,
+		"layout.html":              `<child>{{template "content" .}}</child>`,
+		"item.html":                `<li>{{.}}</li>`,
+		"about.mix.txt":            `about`,
+	})
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	} else if err = ioutil.WriteFile(filepath.Join(dir, "assets", "style.css"), []byte("child"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	th, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if dirs := th.Dirs(); len(dirs) != 2 || dirs[0] != dir || dirs[1] != base {
+		t.Errorf("Dirs() = %v", dirs)
+	}
+
+	if names := strings.Join(th.Names(), ","); names != "about,home,tag" {
+		t.Errorf("Names() = %q", names)
+	}
+
+	for name, want := range map[string]string{
+		"home":  "<child><li>x</li></child>",
+		"tag":   "<child>child tag</child>",
+		"about": "about",
+	} {
+		ext := th.Exts(name)[0]
+		var b strings.Builder
+		if err = th.Execute(&b, name, ext, "x"); err != nil {
+			t.Fatal(err)
+		} else if b.String() != want {
+			t.Errorf("%s = %q, want %q", name, b.String(), want)
+		}
+	}
+
+	assets, err := th.AssetFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	for _, f := range assets {
+		list = append(list, f.Pathname+"="+f.Source)
+	}
+	want := []string{
+		filepath.Join("assets", "base.js") + "=" + filepath.Join(base, "assets", "base.js"),
+		filepath.Join("assets", "style.css") + "=" + filepath.Join(dir, "assets", "style.css"),
+	}
+	if strings.Join(list, " ") != strings.Join(want, " ") {
+		t.Errorf("AssetFiles() = %v, want %v", list, want)
+	}
+
+	outdir := t.TempDir()
+	if err = th.ExportAssets(outdir); err != nil {
+		t.Fatal(err)
+	} else if buf, err := ioutil.ReadFile(filepath.Join(outdir, "assets", "style.css")); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "child" {
+		t.Errorf("exported style.css = %q, want %q", buf, "child")
+	}
+}
+
+func TestLookupDirs(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	for dir, parent := range map[string]string{a: b, b: a} {
+		cfg := `{"parent": "` + parent + `"}`
+		if err := ioutil.WriteFile(filepath.Join(dir, "theme.json"), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := LookupDirs(a); err == nil || !strings.Contains(err.Error(), a+" -> "+b+" -> "+a) {
+		t.Errorf("LookupDirs() with cycle returns %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(b, "theme.json"), []byte(`{"parent": "missing"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupDirs(a); err == nil || !strings.Contains(err.Error(), "parent theme") {
+		t.Errorf("LookupDirs() with missing parent returns %v", err)
+	}
+}
+
+func TestNewDefault(t *testing.T) {
+	themedir := filepath.Join(t.TempDir(), "theme")
+	th, err := New(themedir)
+	if err != nil {
+		t.Fatal(err)
+	} else if dirs := th.Dirs(); len(dirs) != 0 {
+		t.Errorf("Dirs() = %v", dirs)
+	}
+	for _, name := range []string{"home", "article", "archive", "tag", "404", "feed"} {
+		if !th.Exists(name) {
+			t.Errorf("default theme has no template %q", name)
+		}
+	}
+
+	assets, err := th.AssetFiles()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(assets) == 0 {
+		t.Fatal("default theme has no assets")
+	}
+	for _, f := range assets {
+		if buf, err := f.ReadFile(); err != nil {
+			t.Fatal(err)
+		} else if len(buf) == 0 || !strings.HasPrefix(f.Source, defaultName) {
+			t.Errorf("invalid asset %q", f.Source)
+		}
+	}
+
+	// ejected theme is used
+	if err = ExportDefault(themedir); err != nil {
+		t.Fatal(err)
+	} else if th, err = New(themedir); err != nil {
+		t.Fatal(err)
+	} else if dirs := th.Dirs(); len(dirs) != 1 || dirs[0] != themedir {
+		t.Errorf("Dirs() = %v", dirs)
+	}
+}
+
+func TestNewExts(t *testing.T) {
+	base := writeTestTheme(t, map[string]string{
+		"home.mix.html": `base html`,
+		"home.mix.xml":  `base xml`,
+	})
+	dir := writeTestTheme(t, map[string]string{
+		"theme.yaml":    "parent: " + base + "\n",
+		"home.mix.xml":  `<a>{{.}}</a>`,
+		"home.mix.json": `{"a": "{{.}}"}`,
+	})
+	th, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if exts := strings.Join(th.Exts("home"), ","); exts != "html,json,xml" {
+		t.Errorf("Exts() = %q", exts)
+	} else if exts := th.Exts("missing"); len(exts) != 0 {
+		t.Errorf("Exts() of missing template = %v", exts)
+	}
+
+	for ext, want := range map[string]string{
+		"html": "base html",
+		"xml":  `<a><"x"></a>`,
+		"json": `{"a": "<"x">"}`,
+	} {
+		var b strings.Builder
+		if err = th.Execute(&b, "home", ext, `<"x">`); err != nil {
+			t.Fatal(err)
+		} else if b.String() != want {
+			t.Errorf("%s = %q, want %q", ext, b.String(), want)
+		}
+	}
+	if err = th.Execute(ioutil.Discard, "home", "txt", nil); err == nil {
+		t.Error("Execute() of missing extension returns no error")
+	}
+}
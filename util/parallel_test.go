@@ -0,0 +1,116 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelOrder(t *testing.T) {
+	for _, njob := range []int{0, 1, 4, 100} {
+		var running, maxRunning int32
+		var order []int
+		err := Parallel(njob, 20, func(i int) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			// the later indexes finish first
+			time.Sleep(time.Duration(20-i) * time.Millisecond / 10)
+			return nil
+		}, func(i int) error {
+			order = append(order, i)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := make([]int, 20)
+		for i := range want {
+			want[i] = i
+		}
+		if !reflect.DeepEqual(order, want) {
+			t.Errorf("njob %d: done is called in order %v", njob, order)
+		}
+		if njob > 0 && int(maxRunning) > njob {
+			t.Errorf("njob %d: %d goroutines run at the same time", njob, maxRunning)
+		}
+	}
+
+	// nothing to do
+	if err := Parallel(4, 0, func(int) error { return fmt.Errorf("called") }, nil); err != nil {
+		t.Errorf("Parallel() of no indexes returns %s", err)
+	}
+}
+
+func TestParallelError(t *testing.T) {
+	// the error of the lowest index is returned even if it occurs later
+	var done []int
+	err := Parallel(4, 10, func(i int) error {
+		switch i {
+		case 3:
+			time.Sleep(10 * time.Millisecond)
+			return fmt.Errorf("error %d", i)
+		case 5:
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	}, func(i int) error {
+		done = append(done, i)
+		return nil
+	})
+	if err == nil || err.Error() != "error 3" {
+		t.Errorf("Parallel() returns %v, want error 3", err)
+	} else if !reflect.DeepEqual(done, []int{0, 1, 2}) {
+		t.Errorf("done is called with %v, want [0 1 2]", done)
+	}
+
+	// the error of done
+	err = Parallel(2, 10, func(i int) error { return nil }, func(i int) error {
+		if i == 4 {
+			return fmt.Errorf("done %d", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "done 4" {
+		t.Errorf("Parallel() returns %v, want done 4", err)
+	}
+}
+
+func TestParallelCancel(t *testing.T) {
+	// the remaining indexes are not scheduled after the error
+	var called int32
+	err := Parallel(1, 1000, func(i int) error {
+		atomic.AddInt32(&called, 1)
+		if i == 2 {
+			return fmt.Errorf("stop")
+		}
+		return nil
+	}, nil)
+	if err == nil {
+		t.Fatal("Parallel() returns no error")
+	} else if n := atomic.LoadInt32(&called); n != 3 {
+		t.Errorf("fn is called %d times, want 3", n)
+	}
+
+	// Parallel returns after all running fn returned
+	var running int32
+	err = Parallel(4, 100, func(i int) error {
+		atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		time.Sleep(time.Millisecond)
+		return fmt.Errorf("error %d", i)
+	}, nil)
+	if err == nil || err.Error() != "error 0" {
+		t.Errorf("Parallel() returns %v, want error 0", err)
+	} else if n := atomic.LoadInt32(&running); n != 0 {
+		t.Errorf("%d fn are running after Parallel() returned", n)
+	}
+}
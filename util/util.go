@@ -30,11 +30,16 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/mah0x211/mixdown/logger"
 )
 
 // Basename strip directory and suffix from filenames
@@ -91,9 +96,8 @@ func CreateFile(pathname string) (*os.File, error) {
 
 // CopyFile copy srcpath file to dstpath file
 func CopyFile(srcpath, dstpath string) error {
-	log.Printf("copy file %q to %q", srcpath, dstpath)
+	// skip dotfile
 	if strings.HasPrefix(filepath.Base(srcpath), ".") {
-		log.Printf("skip dotfile %q", srcpath)
 		return nil
 	}
 
@@ -144,9 +148,9 @@ func CopyDir(srcdir, dstdir string) error {
 		src *os.File
 	)
 
-	log.Printf("copy dir %q to %q", srcdir, dstdir)
+	logger.Debugf("copy dir %q to %q", srcdir, dstdir)
 	if strings.HasPrefix(filepath.Base(srcdir), ".") {
-		log.Printf("skip dotfile %q", srcdir)
+		logger.Debugf("skip dotfile %q", srcdir)
 		return nil
 	}
 
@@ -182,13 +186,97 @@ func CopyDir(srcdir, dstdir string) error {
 			}
 		} else if err != nil {
 			return err
-		} else if err = CopyFile(srcname, dstname); err != nil {
+		} else if strings.HasPrefix(finfos[0].Name(), ".") {
+			logger.Debugf("skip dotfile %q", srcname)
+			continue
+		}
+
+		logger.Debugf("copy file %q to %q", srcname, dstname)
+		if err = CopyFile(srcname, dstname); err != nil {
 			if !os.IsNotExist(err) {
 				return err
 			}
-			log.Printf("failed to copy file %q to %q - %s", srcname, dstname, err)
+			logger.Warnf("failed to copy file %q to %q - %s", srcname, dstname, err)
+		}
+	}
+}
+
+// Parallel calls fn with each index of [0, n) on up to njob goroutines.
+// if njob is less than 1, the number of CPUs is used.
+// done is called on the calling goroutine in ascending order of the index as
+// soon as fn has returned for that index and all preceding indexes, so that
+// the caller can emit the results in a deterministic order.
+// it stops scheduling the remaining indexes and returns the error of the
+// lowest index if fn or done returns an error.
+func Parallel(njob, n int, fn func(i int) error, done func(i int) error) error {
+	if njob < 1 {
+		njob = runtime.NumCPU()
+	}
+	if njob > n {
+		njob = n
+	}
+
+	errs := make([]error, n)
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	// the lowest index of which fn returned an error. the indexes after it
+	// are skipped, and the indexes before it are always done since they have
+	// been dispatched before it.
+	failed := int64(n)
+	fail := func(i int) {
+		for {
+			cur := atomic.LoadInt64(&failed)
+			if int64(i) >= cur || atomic.CompareAndSwapInt64(&failed, cur, int64(i)) {
+				return
+			}
+		}
+	}
+
+	// dispatch indexes to workers
+	queue := make(chan int)
+	quit := make(chan struct{})
+	go func() {
+		defer close(queue)
+		for i := 0; i < n && int64(i) < atomic.LoadInt64(&failed); i++ {
+			select {
+			case queue <- i:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < njob; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if int64(i) < atomic.LoadInt64(&failed) {
+					if errs[i] = fn(i); errs[i] != nil {
+						fail(i)
+					}
+				}
+				close(finished[i])
+			}
+		}()
+	}
+
+	// collect results in order
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		<-finished[i]
+		if err = errs[i]; err == nil && done != nil {
+			err = done(i)
 		}
 	}
+	close(quit)
+	wg.Wait()
+
+	return err
 }
 
 // ExecCommand execute a first argument as command with remaining arguments as
@@ -203,6 +291,25 @@ func ExecCommand(args ...string) ([]byte, error) {
 	return bytes.TrimSpace(out), nil
 }
 
+// Slugify converts s into a lowercase string that consists of letters, digits
+// and hyphens, e.g. "Hello, World!" is "hello-world"
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, c := range strings.ToLower(s) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
 // GenChecksum generate a SHA256 checksum of specified data
 func GenChecksum(data []byte) string {
 	chksum := sha256.Sum256(data)
@@ -1 +1,20 @@
 package util
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Go 1.16 -- release  ", "go-1-16-release"},
+		{"日本語 タイトル", "日本語-タイトル"},
+		{"don't_stop", "don-t-stop"},
+		{"!?", ""},
+	} {
+		if got := Slugify(c.s); got != c.want {
+			t.Errorf("Slugify(%q) = %q, want %q", c.s, got, c.want)
+		}
+	}
+}
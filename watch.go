@@ -0,0 +1,225 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mah0x211/mixdown/builder"
+	"github.com/mah0x211/mixdown/logger"
+	"github.com/mah0x211/mixdown/theme"
+	"github.com/mah0x211/mixdown/util"
+	"github.com/mah0x211/mixdown/watch"
+)
+
+// interval of polling the changes of files
+const watchInterval = time.Second
+
+// interrupted returns a channel that is closed when the process receives
+// SIGINT or SIGTERM
+func interrupted() <-chan struct{} {
+	done := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		signal.Stop(sig)
+		logger.Infof("received signal %q", s)
+		close(done)
+	}()
+
+	return done
+}
+
+// take the snapshot of the theme, data, config and tracked files
+func (m *Mixdown) snapshot(cfgFiles []string) (watch.Snapshot, error) {
+	out, err := util.ExecCommand("git", "ls-files", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to util.ExecCommand(): %s", err)
+	}
+
+	// ignore the output files that may be tracked
+	outdir := m.OutDir + string(filepath.Separator)
+	pathnames := append(m.themeDirs(), m.DataDir)
+	pathnames = append(pathnames, cfgFiles...)
+	for _, src := range strings.Split(string(out), "\000") {
+		if src != "" && !strings.HasPrefix(src, outdir) {
+			pathnames = append(pathnames, src)
+		}
+	}
+	snap, err := watch.Take(pathnames...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch.Take(): %s", err)
+	}
+
+	// documents are made from the commit logs
+	if out, err = util.ExecCommand("git", "rev-parse", "HEAD"); err == nil {
+		snap["git:HEAD"] = string(out)
+	}
+
+	return snap, nil
+}
+
+// reload the config files and re-apply the command-line parameters
+func (m *Mixdown) reloadConfig(cfgFiles []string) error {
+	cfg := builder.NewConfig()
+	fs := flag.NewFlagSet(flag.CommandLine.Name(), flag.ContinueOnError)
+	fields := bindFlags(fs, cfg, false)
+	flag.Visit(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) != nil {
+			fs.Set(f.Name, f.Value.String())
+		}
+	})
+	if err := loadConfig(cfg, fs, fields, cfgFiles); err != nil {
+		return err
+	}
+
+	// output directories cannot be changed while watching
+	cfg.OutDir = m.OutDir
+	cfg.Backup = m.Backup
+	cfg.DryRun = m.DryRun
+	cfg.Force = m.Force
+	b, err := builder.New(cfg)
+	if err != nil {
+		return err
+	}
+	b.Hooks = m.Hooks
+	m.Builder = b
+
+	return nil
+}
+
+// isConfigFile returns true if pathname is one of the loaded config files
+func isConfigFile(cfgFiles []string, pathname string) bool {
+	for _, cfgFile := range cfgFiles {
+		if pathname == cfgFile {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the theme directory and the directories of its parent themes. the
+// theme directory is always included to detect that it is created while the
+// default theme is used.
+func (m *Mixdown) themeDirs() []string {
+	dirs := []string{m.ThemeDir}
+	if m.Theme != nil {
+		dirs = append(dirs, m.Theme.Dirs()...)
+	} else if parents, err := theme.LookupDirs(m.ThemeDir); err == nil {
+		// the error is reported by the build
+		dirs = append(dirs, parents...)
+	}
+	return dirs
+}
+
+// isAssetFile returns true if pathname is a file in the asset directories
+func (m *Mixdown) isAssetFile(pathname string) bool {
+	for _, dir := range m.themeDirs() {
+		rel, err := filepath.Rel(dir, pathname)
+		if err == nil && !strings.HasPrefix(rel, "..") &&
+			strings.ContainsRune(rel, filepath.Separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild the site with the changed pathnames
+func (m *Mixdown) rebuild(cfgFiles []string, changes []string) error {
+	assetsOnly := m.Theme != nil
+	for _, pathname := range changes {
+		logger.Infof("changed %q", pathname)
+		if isConfigFile(cfgFiles, pathname) {
+			if err := m.reloadConfig(cfgFiles); err != nil {
+				return err
+			}
+			assetsOnly = false
+		} else if !m.isAssetFile(pathname) {
+			assetsOnly = false
+		} else if _, err := os.Stat(pathname); err != nil {
+			// removed asset file remains in the output directory
+			assetsOnly = false
+		}
+	}
+
+	// export asset files only
+	if assetsOnly {
+		logger.Infof("export assets directories")
+		if err := m.ExportAssets(); err != nil {
+			return fmt.Errorf("failed to ExportAssets(): %s", err)
+		}
+		return nil
+	}
+
+	return m.build()
+}
+
+// watch polls the changes of the theme, data, config and tracked files, and
+// rebuilds the site until stop is closed. rebuilt is called after every
+// successful rebuild.
+func (m *Mixdown) watch(cfgFiles []string, stop <-chan struct{}, rebuilt func()) error {
+	snap, err := m.snapshot(cfgFiles)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("watch changes every %s", watchInterval)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := m.snapshot(cfgFiles)
+		if err != nil {
+			logger.Errorf("failed to snapshot(): %s", err)
+			continue
+		}
+		changes := snap.Changes(cur)
+		if len(changes) == 0 {
+			continue
+		}
+		snap = cur
+
+		logger.Infof("rebuild")
+		if err = m.rebuild(cfgFiles, changes); err != nil {
+			// keep watching to wait for fixes
+			logger.Errorf("failed to rebuild(): %s", err)
+		} else if rebuilt != nil {
+			rebuilt()
+		}
+	}
+}
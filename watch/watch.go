@@ -0,0 +1,97 @@
+//
+// Copyright (C) 2026 Masatoshi Fukunaga
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.  IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+// Created by Masatoshi Fukunaga on 26/10/18
+//
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Snapshot holds the modification time and size of files by pathname
+type Snapshot map[string]string
+
+// Take walks the pathnames and returns the snapshot of files under them.
+// nonexistent pathnames are ignored.
+func Take(pathnames ...string) (Snapshot, error) {
+	s := make(Snapshot)
+	for _, root := range pathnames {
+		// walk into the real directory of symlink
+		realpath, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		err = filepath.Walk(realpath, func(pathname string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			} else if info.IsDir() {
+				return nil
+			}
+
+			// use the pathname under the root
+			if rel, err := filepath.Rel(realpath, pathname); err != nil {
+				return err
+			} else if rel != "." {
+				pathname = filepath.Join(root, rel)
+			} else {
+				pathname = root
+			}
+			s[pathname] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Changes returns the sorted pathnames that were added, modified or removed
+// in the newer snapshot
+func (s Snapshot) Changes(newer Snapshot) []string {
+	changes := make([]string, 0)
+	for pathname, stat := range newer {
+		if s[pathname] != stat {
+			changes = append(changes, pathname)
+		}
+	}
+	for pathname := range s {
+		if _, ok := newer[pathname]; !ok {
+			changes = append(changes, pathname)
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
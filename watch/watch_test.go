@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTakeAndChanges(t *testing.T) {
+	dir := t.TempDir()
+	theme := filepath.Join(dir, "theme")
+	config := filepath.Join(dir, "config.json")
+	write := func(pathname, text string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		} else if err = ioutil.WriteFile(pathname, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(theme, "home.mix.html"), "home")
+	write(filepath.Join(theme, "assets", "style.css"), "body{}")
+	write(filepath.Join(theme, "removed.html"), "removed")
+	write(config, "{}")
+
+	// the symlink is walked with the pathname under it
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(theme, link); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := Take(theme, config, link, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		config,
+		filepath.Join(link, "assets", "style.css"),
+		filepath.Join(link, "home.mix.html"),
+		filepath.Join(link, "removed.html"),
+		filepath.Join(theme, "assets", "style.css"),
+		filepath.Join(theme, "home.mix.html"),
+		filepath.Join(theme, "removed.html"),
+	}
+	got := (Snapshot{}).Changes(snap)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files of snapshot = %v, want %v", got, want)
+	}
+	if got = snap.Changes(snap); len(got) != 0 {
+		t.Errorf("Changes() of the same snapshot = %v", got)
+	}
+
+	// modify, add and remove
+	snap, err = Take(theme, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(filepath.Join(theme, "home.mix.html"), "home page")
+	write(filepath.Join(theme, "assets", "app.js"), "app")
+	if err = os.Remove(filepath.Join(theme, "removed.html")); err != nil {
+		t.Fatal(err)
+	}
+	// the modification without the size change
+	mtime := time.Now().Add(time.Hour)
+	if err = os.Chtimes(config, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	cur, err := Take(theme, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		config,
+		filepath.Join(theme, "assets", "app.js"),
+		filepath.Join(theme, "home.mix.html"),
+		filepath.Join(theme, "removed.html"),
+	}
+	if got = snap.Changes(cur); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Changes() = %v, want %v", got, want)
+	}
+}